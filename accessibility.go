@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"maps"
+	"math"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// BallPalette is a set of ball colors. A tinted palette draws every ball from the white strips multiplied
+// by its colors, as the painted strips only come in the default colors.
+type BallPalette struct {
+	Colors, BrightColors, TextColors [6]color.RGBA
+	Tinted                           bool
+}
+
+// gBallTinted is set while the applied palette is tinted.
+var gBallTinted bool = false
+
+var globalBallPalettes map[string]BallPalette = map[string]BallPalette{
+	"default": {globalBallColors, globalBrightBallColors, globalTextBallColors, false},
+	// Okabe-Ito colors, which stay apart for the common kinds of color blindness
+	"high_contrast": {
+		Tinted: true,
+		Colors: [6]color.RGBA{
+			{0, 114, 178, 255}, {240, 228, 66, 255}, {213, 94, 0, 255},
+			{0, 158, 115, 255}, {204, 121, 167, 255}, {255, 255, 255, 255},
+		},
+		BrightColors: [6]color.RGBA{
+			{86, 180, 233, 255}, {255, 245, 140, 255}, {240, 150, 80, 255},
+			{80, 220, 170, 255}, {235, 175, 210, 255}, {255, 255, 255, 255},
+		},
+		TextColors: [6]color.RGBA{
+			{86, 180, 233, 255}, {240, 228, 66, 255}, {230, 159, 0, 255},
+			{0, 200, 140, 255}, {220, 140, 190, 255}, {255, 255, 255, 255},
+		},
+	},
+	"monochrome": {
+		Tinted: true,
+		Colors: [6]color.RGBA{
+			{60, 60, 60, 255}, {230, 230, 230, 255}, {110, 110, 110, 255},
+			{160, 160, 160, 255}, {30, 30, 30, 255}, {255, 255, 255, 255},
+		},
+		BrightColors: [6]color.RGBA{
+			{140, 140, 140, 255}, {255, 255, 255, 255}, {180, 180, 180, 255},
+			{210, 210, 210, 255}, {110, 110, 110, 255}, {255, 255, 255, 255},
+		},
+		TextColors: [6]color.RGBA{
+			{150, 150, 150, 255}, {255, 255, 255, 255}, {190, 190, 190, 255},
+			{220, 220, 220, 255}, {120, 120, 120, 255}, {255, 255, 255, 255},
+		},
+	},
+}
+
+func GetBallPaletteNames() []string {
+	return slices.Sorted(maps.Keys(globalBallPalettes))
+}
+
+func ApplyBallPalette(theName string) error {
+	palette, found := globalBallPalettes[theName]
+	if !found {
+		return fmt.Errorf("unknown palette %q", theName)
+	}
+	globalBallColors = palette.Colors
+	globalBrightBallColors = palette.BrightColors
+	globalTextBallColors = palette.TextColors
+	gBallTinted = palette.Tinted
+	return nil
+}
+
+// GetBallTint returns the color a ball strip of theType is drawn with, theColor multiplied by the palette color
+// when the palette is tinted.
+func GetBallTint(theType int32, theColor color.RGBA) color.RGBA {
+	if !gBallTinted {
+		return theColor
+	}
+	tint := globalBallColors[theType]
+	return color.RGBA{
+		uint8(uint16(theColor.R) * uint16(tint.R) / 255), uint8(uint16(theColor.G) * uint16(tint.G) / 255),
+		uint8(uint16(theColor.B) * uint16(tint.B) / 255), theColor.A,
+	}
+}
+
+// DrawBallSymbol draws the shape that identifies a ball type without relying on its color.
+// The symbol stays upright while the ball rolls, so it is readable at any point of the curve.
+func DrawBallSymbol(theType int32, theX, theY, theSize float32, theAlpha uint8) {
+	outline := color.RGBA{0, 0, 0, theAlpha}
+	fill := color.RGBA{255, 255, 255, theAlpha}
+	center := rl.NewVector2(theX, theY)
+	switch theType {
+	case 0: // circle
+		rl.DrawCircleV(center, theSize+1, outline)
+		rl.DrawCircleV(center, theSize, fill)
+		rl.DrawCircleV(center, theSize*0.45, outline)
+	case 1: // triangle
+		rl.DrawPoly(center, 3, theSize*1.4, -90, outline)
+		rl.DrawPoly(center, 3, theSize*1.4-2, -90, fill)
+	case 2: // square
+		rl.DrawPoly(center, 4, theSize*1.3, 45, outline)
+		rl.DrawPoly(center, 4, theSize*1.3-1.5, 45, fill)
+	case 3: // diamond
+		rl.DrawPoly(center, 4, theSize*1.4, 0, outline)
+		rl.DrawPoly(center, 4, theSize*1.4-1.5, 0, fill)
+	case 4: // star
+		drawStar(center, theSize*1.5, outline)
+		drawStar(center, theSize*1.5-2, fill)
+	case 5: // cross
+		thick := max(theSize*0.6, 1)
+		rl.DrawRectangleRec(rl.NewRectangle(theX-theSize-1, theY-thick/2-1, 2*theSize+2, thick+2), outline)
+		rl.DrawRectangleRec(rl.NewRectangle(theX-thick/2-1, theY-theSize-1, thick+2, 2*theSize+2), outline)
+		rl.DrawRectangleRec(rl.NewRectangle(theX-theSize, theY-thick/2, 2*theSize, thick), fill)
+		rl.DrawRectangleRec(rl.NewRectangle(theX-thick/2, theY-theSize, thick, 2*theSize), fill)
+	}
+}
+
+func drawStar(theCenter rl.Vector2, theRadius float32, theColor color.RGBA) {
+	var points [11]rl.Vector2
+	points[0] = theCenter
+	for i := range 5 {
+		outer := float64(i)*2*math.Pi/5 - math.Pi/2
+		inner := outer + math.Pi/5
+		points[1+i*2] = rl.NewVector2(theCenter.X+theRadius*float32(math.Cos(outer)), theCenter.Y+theRadius*float32(math.Sin(outer)))
+		points[2+i*2] = rl.NewVector2(theCenter.X+theRadius*0.45*float32(math.Cos(inner)), theCenter.Y+theRadius*0.45*float32(math.Sin(inner)))
+	}
+	// triangles are drawn counter-clockwise around the center
+	for i := range 10 {
+		rl.DrawTriangle(theCenter, points[1+(i+1)%10], points[1+i], theColor)
+	}
+}
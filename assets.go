@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type AssetKind int32
+
+const (
+	AssetKind_Texture AssetKind = iota
+	AssetKind_Curve
+)
+
+var globalAssetKindNames [2]string = [2]string{"texture", "curve"}
+
+// Asset is a resource shared by everyone who acquired it, it is unloaded when the last handle is released.
+// Assets loaded from a file are keyed by the file path, generated ones by a name the generator picks.
+// Sources are the files the asset was made from, when one of them changes the asset is evicted.
+type Asset struct {
+	Key      string
+	Kind     AssetKind
+	RefCount int32
+	Sources  []string
+	Texture  rl.Texture2D
+	Curve    []PathPoint
+}
+
+// AssetHandle is one reference to an asset, every handle is released exactly once.
+type AssetHandle struct {
+	Asset *Asset
+}
+
+type AssetMgr struct {
+	Assets map[string]*Asset
+}
+
+var globalAssets *AssetMgr = NewAssetMgr()
+
+func NewAssetMgr() *AssetMgr {
+	return &AssetMgr{Assets: make(map[string]*Asset)}
+}
+
+// Acquire returns a handle to the asset of theKey. The asset is loaded by theLoad if nobody holds it yet.
+func (mgr *AssetMgr) Acquire(theKey string, theKind AssetKind, theSources []string, theLoad func(*Asset) error) (AssetHandle, error) {
+	asset, found := mgr.Assets[theKey]
+	if !found {
+		asset = &Asset{Key: theKey, Kind: theKind, Sources: theSources}
+		if err := theLoad(asset); err != nil {
+			return AssetHandle{}, err
+		}
+		mgr.Assets[theKey] = asset
+	} else if asset.Kind != theKind {
+		return AssetHandle{}, fmt.Errorf("%s: is a %s, not a %s", theKey, globalAssetKindNames[asset.Kind], globalAssetKindNames[theKind])
+	}
+	asset.RefCount++
+	return AssetHandle{asset}, nil
+}
+
+func (mgr *AssetMgr) AcquireTexture(theFilePath string) (AssetHandle, error) {
+	return mgr.Acquire(theFilePath, AssetKind_Texture, []string{theFilePath}, func(theAsset *Asset) error {
+		var err error
+		if theAsset.Texture, err = LoadGameTexture(theFilePath); err != nil {
+			return err
+		}
+		SetGameTextureFilter(theAsset.Texture)
+		return nil
+	})
+}
+
+func (mgr *AssetMgr) AcquireCurve(theFilePath string) (AssetHandle, error) {
+	return mgr.Acquire(theFilePath, AssetKind_Curve, []string{theFilePath}, func(theAsset *Asset) error {
+		var err error
+		theAsset.Curve, err = LoadCurveData(theFilePath)
+		return err
+	})
+}
+
+// Release drops the reference of theHandle and unloads the asset if it was the last one.
+func (mgr *AssetMgr) Release(theHandle *AssetHandle) {
+	asset := theHandle.Asset
+	if asset == nil {
+		return
+	}
+	theHandle.Asset = nil
+	asset.RefCount--
+	if asset.RefCount > 0 {
+		return
+	}
+	switch asset.Kind {
+	case AssetKind_Texture:
+		UnloadGameTexture(asset.Texture)
+	}
+	if mgr.Assets[asset.Key] == asset {
+		delete(mgr.Assets, asset.Key)
+	}
+}
+
+// Evict forgets the assets made from theFilePath, so the next acquire loads them again.
+// Evicted assets stay loaded until their last handle is released.
+func (mgr *AssetMgr) Evict(theFilePath string) int {
+	count := 0
+	for key, asset := range mgr.Assets {
+		if slices.ContainsFunc(asset.Sources, func(source string) bool { return SameAssetPath(source, theFilePath) }) {
+			delete(mgr.Assets, key)
+			count++
+		}
+	}
+	return count
+}
+
+// ReportLeaks logs the assets still held, at shutdown every one of them is a missing release.
+func (mgr *AssetMgr) ReportLeaks() int {
+	for _, key := range slices.Sorted(maps.Keys(mgr.Assets)) {
+		asset := mgr.Assets[key]
+		slog.Warn("asset leaked", "kind", globalAssetKindNames[asset.Kind], "key", key, "references", asset.RefCount)
+	}
+	return len(mgr.Assets)
+}
+
+// LevelScope holds the assets of one level. The board acquires the scope of the next level before it releases
+// the old one, so restarting a level keeps everything it shares with itself loaded.
+type LevelScope struct {
+	Name    string
+	Handles []AssetHandle
+}
+
+func NewLevelScope(theName string) *LevelScope {
+	return &LevelScope{Name: theName}
+}
+
+func (scope *LevelScope) hold(theHandle AssetHandle, theErr error) (*Asset, error) {
+	if theErr != nil {
+		return nil, theErr
+	}
+	scope.Handles = append(scope.Handles, theHandle)
+	return theHandle.Asset, nil
+}
+
+func (scope *LevelScope) AcquireTexture(theFilePath string) (rl.Texture2D, error) {
+	asset, err := scope.hold(globalAssets.AcquireTexture(theFilePath))
+	if err != nil {
+		return rl.Texture2D{}, err
+	}
+	return asset.Texture, nil
+}
+
+// AcquireGeneratedTexture returns the texture of theKey, it is only generated from theSources if no scope holds it yet.
+func (scope *LevelScope) AcquireGeneratedTexture(theKey string, theSources []string, theGenerate func() (rl.Texture2D, error)) (rl.Texture2D, error) {
+	asset, err := scope.hold(globalAssets.Acquire(theKey, AssetKind_Texture, theSources, func(theAsset *Asset) error {
+		texture, err := theGenerate()
+		theAsset.Texture = texture
+		return err
+	}))
+	if err != nil {
+		return rl.Texture2D{}, err
+	}
+	return asset.Texture, nil
+}
+
+func (scope *LevelScope) AcquireCurve(theFilePath string) ([]PathPoint, error) {
+	asset, err := scope.hold(globalAssets.AcquireCurve(theFilePath))
+	if err != nil {
+		return nil, err
+	}
+	return asset.Curve, nil
+}
+
+// Release gives back every asset of the level.
+func (scope *LevelScope) Release() {
+	if scope == nil {
+		return
+	}
+	for i := range scope.Handles {
+		globalAssets.Release(&scope.Handles[i])
+	}
+	scope.Handles = nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const AtlasSize int32 = 2048
+const AtlasPadding int32 = 2
+
+// Sprite is where the image of a texture key is drawn from, either a region of an atlas or a texture of its own.
+type Sprite struct {
+	Texture rl.Texture2D
+	Source  rl.Rectangle
+	Layout  SpriteLayout
+	Atlas   int32
+}
+
+// gSprites is the lookup table from texture keys to sprites, it is rebuilt whenever the textures change.
+var gSprites [Texture_Max]Sprite
+var gAtlases []rl.Texture2D
+
+// globalAtlasKeys are the ball, power and effect images, which are packed together so drawing the chains
+// and particles rarely switches textures.
+var globalAtlasKeys []TextureKey = func() []TextureKey {
+	keys := []TextureKey{
+		Texture_BallDots, Texture_BallExplosion, Texture_BallShadow, Texture_Sparkle, Texture_Explosion,
+		Texture_AccuracyLight, Texture_BackwardsLight, Texture_SlowLight,
+	}
+	for set := range ColorTexture_Max {
+		keys = append(keys, globalColorTextures[set][:]...)
+	}
+	return keys
+}()
+
+func GetSprite(theKey TextureKey) *Sprite {
+	return &gSprites[theKey]
+}
+
+// GetColorSprite returns the sprite of a ball color, or the white one to be tinted with GetBallTint
+// when the palette is tinted.
+func GetColorSprite(theSet ColorTexture, theType int32) *Sprite {
+	if gBallTinted {
+		theType = WhiteBallType
+	}
+	return &gSprites[globalColorTextures[theSet][theType]]
+}
+
+// GetCell returns the source rectangle of a cell of the sprite, counting cells row by row.
+func (sprite *Sprite) GetCell(theCell int32) rl.Rectangle {
+	width, height := sprite.Source.Width/float32(sprite.Layout.Cols), sprite.Source.Height/float32(sprite.Layout.Rows)
+	col, row := theCell%sprite.Layout.Cols, theCell/sprite.Layout.Cols
+	return rl.NewRectangle(sprite.Source.X+float32(col)*width, sprite.Source.Y+float32(row)*height, width, height)
+}
+
+// BuildAtlases packs the atlas keys into as few atlases as possible with a shelf packer, tallest images first.
+// The other keys get a sprite covering their own texture. In headless mode only the regions are computed.
+func BuildAtlases() {
+	UnloadAtlases()
+	for key := range Texture_Max {
+		texture := gTextures[key]
+		gSprites[key] = Sprite{texture, rect(0, 0, texture.Width, texture.Height), gTextureLayouts[key], -1}
+	}
+
+	keys := slices.Clone(globalAtlasKeys)
+	slices.SortStableFunc(keys, func(a, b TextureKey) int { return cmp.Compare(gTextures[b].Height, gTextures[a].Height) })
+
+	type placement struct {
+		Key  TextureKey
+		X, Y int32
+	}
+	var pages [][]placement
+	var page_heights []int32
+	var x, y, shelf_height int32 = 0, 0, 0
+	for _, key := range keys {
+		texture := gTextures[key]
+		width, height := texture.Width+AtlasPadding, texture.Height+AtlasPadding
+		if width > AtlasSize || height > AtlasSize {
+			continue
+		}
+		if x+width > AtlasSize {
+			x, y, shelf_height = 0, y+shelf_height, 0
+		}
+		if len(pages) == 0 || y+height > AtlasSize {
+			pages = append(pages, nil)
+			page_heights = append(page_heights, 0)
+			x, y, shelf_height = 0, 0, 0
+		}
+		page := len(pages) - 1
+		pages[page] = append(pages[page], placement{key, x, y})
+		page_heights[page] = max(page_heights[page], y+height)
+		x += width
+		shelf_height = max(shelf_height, height)
+	}
+
+	for page, placements := range pages {
+		var atlas rl.Texture2D
+		if !globalHeadless {
+			image := rl.GenImageColor(int(AtlasSize), int(page_heights[page]), rl.Blank)
+			for _, placed := range placements {
+				texture := gTextures[placed.Key]
+				if texture.ID == 0 {
+					continue
+				}
+				source := rl.LoadImageFromTexture(texture)
+				rl.ImageDraw(image, source, rect(0, 0, source.Width, source.Height), rect(placed.X, placed.Y, source.Width, source.Height), rl.White)
+				rl.UnloadImage(source)
+			}
+			atlas = rl.LoadTextureFromImage(image)
+			rl.UnloadImage(image)
+			SetGameTextureFilter(atlas)
+		} else {
+			atlas = rl.Texture2D{Width: AtlasSize, Height: page_heights[page]}
+		}
+		gAtlases = append(gAtlases, atlas)
+
+		for _, placed := range placements {
+			sprite := &gSprites[placed.Key]
+			sprite.Texture, sprite.Atlas = atlas, int32(page)
+			sprite.Source.X, sprite.Source.Y = float32(placed.X), float32(placed.Y)
+		}
+	}
+}
+
+func UnloadAtlases() {
+	for _, atlas := range gAtlases {
+		UnloadGameTexture(atlas)
+	}
+	gAtlases = gAtlases[:0]
+}
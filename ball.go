@@ -3,7 +3,6 @@ package main
 import (
 	"image/color"
 	"math"
-	"math/rand"
 	"slices"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -27,6 +26,11 @@ var globalTextBallColors [6]color.RGBA = [6]color.RGBA{
 	{0, 255, 0, 255}, {255, 0, 255, 255}, {255, 255, 255, 255},
 }
 
+var globalBallColorNames [6]string = [6]string{"blue", "yellow", "red", "green", "purple", "white"}
+
+// WhiteBallType is the ball type whose strips are white, tinted palettes draw every ball from them.
+const WhiteBallType int32 = 5
+
 type Ball struct {
 	Id                       int32
 	Type                     int32
@@ -49,6 +53,7 @@ type Ball struct {
 	PowerFade                int32
 	ComboCount, ComboScore   int32
 	GapCount, GapBonus       int32
+	ListIndex                int
 }
 
 type PowerType int32
@@ -62,21 +67,49 @@ const (
 	PowerType_None PowerType = 4
 )
 
+var globalPowerTypeNames [PowerType_Max]string = [PowerType_Max]string{"bomb", "slow", "accuracy", "backwards"}
+
 type Particle struct {
 	X, Y, VX, VY float32
 	Size         int32
 }
 
+// Released balls and explosion particles are kept for reuse, so clearing balls does not allocate.
+var gBallPool []*Ball
+var gParticlePool []*[60]Particle
+
 func NewBall() *Ball {
-	tmp := &Ball{}
-	globalIdGen++
-	tmp.Id = globalIdGen
-	tmp.PowerType = PowerType_Max
-	tmp.DestPowerType = PowerType_Max
+	var tmp *Ball = nil
+	if num := len(gBallPool); num > 0 {
+		tmp = gBallPool[num-1]
+		gBallPool[num-1] = nil
+		gBallPool = gBallPool[:num-1]
+	} else {
+		tmp = &Ball{}
+	}
+	tmp.Init()
 	return tmp
 }
 
+// ReleaseBall hands a ball that is in no list anymore back to the pool.
+func ReleaseBall(theBall *Ball) {
+	theBall.BeforeDestroy()
+	gBallPool = append(gBallPool, theBall)
+}
+
+func (ball *Ball) Init() {
+	*ball = Ball{}
+	globalIdGen++
+	ball.Id = globalIdGen
+	ball.PowerType = PowerType_Max
+	ball.DestPowerType = PowerType_Max
+	ball.ListIndex = -1
+}
+
 func (ball *Ball) BeforeDestroy() {
+	if ball.Particles != nil {
+		gParticlePool = append(gParticlePool, ball.Particles)
+	}
 	ball.Particles = nil
 }
 
@@ -92,41 +125,73 @@ func (ball *Ball) CollidesWithPhysically(theBall *Ball, thePad int32) bool {
 
 func (ball *Ball) DoDraw() {
 	if ball.PowerType == PowerType_None {
-		the_texture := gTextures[Texture_BlueBall+TextureKey(ball.Type)]
-		num_rows := the_texture.Height / the_texture.Width
-		frame := (ball.StartFrame + int32(ball.WayPoint)) % num_rows
-		rl.DrawTexturePro(the_texture, rl.NewRectangle(0, float32(frame*the_texture.Width), float32(the_texture.Width), float32(the_texture.Width)),
+		sprite := GetColorSprite(ColorTexture_Ball, ball.Type)
+		frame := (ball.StartFrame + int32(ball.WayPoint)) % sprite.Layout.Rows
+		rl.DrawTexturePro(sprite.Texture, sprite.GetCell(frame),
 			rl.NewRectangle(ball.X, ball.Y, float32(DefaultBallRadius*2), float32(DefaultBallRadius*2)), rl.NewVector2(float32(DefaultBallRadius), float32(DefaultBallRadius)),
-			-ball.Rotation*rl.Rad2deg, rl.White,
+			-ball.Rotation*rl.Rad2deg, GetBallTint(ball.Type, rl.White),
 		)
 	} else {
 		ball.DrawPower()
 	}
 }
 
+// Draw draws the whole ball. The ball drawer calls the passes separately instead, so that a layer
+// draws all bodies from the ball atlas before it switches to additive blending for the lights.
 func (ball *Ball) Draw() {
+	ball.DrawBody()
+	ball.DrawParticles()
+	if ball.HasLights() {
+		rl.BeginBlendMode(rl.BlendAdditive)
+		ball.DrawLights()
+		rl.EndBlendMode()
+	}
+	ball.DrawSymbol()
+}
+
+func (ball *Ball) DrawBody() {
 	if ball.ClearCount != 0 {
 		ball.DrawExplosion()
 	} else {
 		ball.DoDraw()
-		if ball.PowerFade != 0 && (ball.PowerFade&0x10) != 0 {
-			rl.BeginBlendMode(rl.BlendAdditive)
-			ball.DoDraw()
-		}
-		if globalBallBlink {
-			rl.BeginBlendMode(rl.BlendAdditive)
-			ball.DoDraw()
-		}
 	}
+}
+
+// DrawSymbol draws the accessibility symbol of the ball. It comes after the lights, which would wash out its outline.
+func (ball *Ball) DrawSymbol() {
+	if ball.ClearCount == 0 && globalSettings.Accessibility.BallSymbols {
+		DrawBallSymbol(ball.Type, ball.X, ball.Y, float32(DefaultBallRadius)*0.35, 255)
+	}
+}
 
-	rl.EndBlendMode()
+// HasLights tells whether DrawLights draws anything, so a layer without lights keeps its blend mode.
+func (ball *Ball) HasLights() bool {
+	return ball.ClearCount == 0 && (ball.PowerType == PowerType_Bomb || ball.PowerFade&0x10 != 0 || globalBallBlink)
+}
+
+// DrawLights draws the additive parts of the ball, the caller sets the blend mode.
+func (ball *Ball) DrawLights() {
+	if ball.ClearCount != 0 {
+		return
+	}
+	if ball.PowerType == PowerType_Bomb {
+		ball.DrawBombLight()
+	}
+	if ball.PowerFade&0x10 != 0 {
+		ball.DoDraw()
+	}
+	if globalBallBlink {
+		ball.DoDraw()
+	}
 }
 
 func (ball *Ball) DrawBomb() {
-	texture := gTextures[Texture_BlueBomb+TextureKey(ball.Type)]
-	x, y := ball.X-float32(texture.Width/2), ball.Y-float32(texture.Height/2)
-	rl.DrawTextureV(texture, rl.NewVector2(x, y), rl.White)
+	sprite := GetColorSprite(ColorTexture_Bomb, ball.Type)
+	x, y := ball.X-float32(int32(sprite.Source.Width)/2), ball.Y-float32(int32(sprite.Source.Height)/2)
+	rl.DrawTextureRec(sprite.Texture, sprite.Source, rl.NewVector2(x, y), GetBallTint(ball.Type, rl.White))
+}
 
+func (ball *Ball) DrawBombLight() {
 	var alpha int32 = globalBoard.StateCount
 	if alpha%50 <= 9 {
 		alpha = 0
@@ -138,42 +203,44 @@ func (ball *Ball) DrawBomb() {
 		alpha = 200 - (200*(alpha%50)-7000)/15
 	}
 
-	rl.BeginBlendMode(rl.BlendAdditive)
+	sprite := GetColorSprite(ColorTexture_Bomb, ball.Type)
+	x, y := ball.X-float32(int32(sprite.Source.Width)/2), ball.Y-float32(int32(sprite.Source.Height)/2)
 	color := rl.NewColor(uint8(alpha), uint8(alpha), uint8(alpha), 255)
-	light_texture := gTextures[Texture_BlueLight+TextureKey(ball.Type)]
-	rl.DrawTextureV(light_texture, rl.NewVector2(x+7, y+9), color)
-
-	rl.EndBlendMode()
+	light := GetColorSprite(ColorTexture_Light, ball.Type)
+	rl.DrawTextureRec(light.Texture, light.Source, rl.NewVector2(x+7, y+9), GetBallTint(ball.Type, color))
 }
 
 func (ball *Ball) DrawExplosion() {
-	width, height := gTextures[Texture_BallExplosion].Width, gTextures[Texture_BallExplosion].Height
-	image_rows := height / width
-	cell_height := height / image_rows
-	img_x := ball.X - float32(width)/2
-	img_y := ball.Y - float32(cell_height)/2
+	sprite := GetSprite(Texture_BallExplosion)
+	cell := sprite.GetCell(0)
+	img_x := ball.X - cell.Width/2
+	img_y := ball.Y - cell.Height/2
 
 	cel := ball.ClearCount / 3
-	if cel < image_rows {
+	if cel < sprite.Layout.Rows {
 		angle := float32(ball.StartFrame) * math.Pi / 25
-		rl.DrawTexturePro(gTextures[Texture_BallExplosion], rl.NewRectangle(0, float32(cel*cell_height), float32(width), float32(cell_height)),
-			rl.NewRectangle(img_x, img_y, float32(width), float32(cell_height)), rl.NewVector2(0, 0),
+		rl.DrawTexturePro(sprite.Texture, sprite.GetCell(cel),
+			rl.NewRectangle(img_x, img_y, cell.Width, cell.Height), rl.NewVector2(0, 0),
 			angle, globalBrightBallColors[ball.Type],
 		)
 	}
+}
 
-	if ball.Particles != nil {
-		for i := range 60 {
-			particle := &(*ball.Particles)[i]
-			color := globalBrightBallColors[ball.Type]
-			if ball.ClearCount > 20 {
-				color.A = uint8(255 - (255*ball.ClearCount-5100)/20)
-			}
-			rl.DrawRectangleRec(rl.NewRectangle(
-				float32(ball.ClearCount)*particle.VX+particle.X, float32(ball.ClearCount)*particle.VY+particle.Y,
-				float32(particle.Size), float32(particle.Size),
-			), color)
+// DrawParticles draws the bits flying off a clearing ball, they are plain rectangles outside of the atlas.
+func (ball *Ball) DrawParticles() {
+	if ball.ClearCount == 0 || ball.Particles == nil {
+		return
+	}
+	for i := range 60 {
+		particle := &(*ball.Particles)[i]
+		color := globalBrightBallColors[ball.Type]
+		if ball.ClearCount > 20 {
+			color.A = uint8(255 - (255*ball.ClearCount-5100)/20)
 		}
+		rl.DrawRectangleRec(rl.NewRectangle(
+			float32(ball.ClearCount)*particle.VX+particle.X, float32(ball.ClearCount)*particle.VY+particle.Y,
+			float32(particle.Size), float32(particle.Size),
+		), color)
 	}
 }
 
@@ -182,30 +249,31 @@ func (ball *Ball) DrawPower() {
 	case PowerType_Bomb:
 		ball.DrawBomb()
 	case PowerType_SlowDown:
-		ball.DrawStandardPower(Texture_BlueSlow, Texture_SlowLight)
+		ball.DrawStandardPower(ColorTexture_Slow, Texture_SlowLight)
 	case PowerType_Accuracy:
-		ball.DrawStandardPower(Texture_BlueAccuracy, Texture_AccuracyLight)
+		ball.DrawStandardPower(ColorTexture_Accuracy, Texture_AccuracyLight)
 	case PowerType_MoveBackwards:
-		ball.DrawStandardPower(Texture_BlueBackwards, Texture_BackwardsLight)
+		ball.DrawStandardPower(ColorTexture_Backwards, Texture_BackwardsLight)
 	}
 }
 
 func (ball *Ball) DrawShadow() {
 	if ball.ClearCount == 0 {
-		rl.DrawTextureV(gTextures[Texture_BallShadow], rl.NewVector2(
-			ball.X-float32(gTextures[Texture_BallShadow].Width/2)-3,
-			ball.Y-float32(gTextures[Texture_BallShadow].Height/2)+5,
+		sprite := GetSprite(Texture_BallShadow)
+		rl.DrawTextureRec(sprite.Texture, sprite.Source, rl.NewVector2(
+			ball.X-float32(int32(sprite.Source.Width)/2)-3,
+			ball.Y-float32(int32(sprite.Source.Height)/2)+5,
 		), rl.NewColor(0, 0, 0, 128))
 	}
 }
 
-func (ball *Ball) DrawStandardPower(theBallImageId, theBlinkImageId TextureKey) {
-	ball_texture := gTextures[theBallImageId+TextureKey(ball.Type)]
-	blink_texture := gTextures[theBlinkImageId]
+func (ball *Ball) DrawStandardPower(theBallImageSet ColorTexture, theBlinkImageId TextureKey) {
+	ball_sprite := GetColorSprite(theBallImageSet, ball.Type)
+	blink_sprite := GetSprite(theBlinkImageId)
 
-	rl.DrawTexturePro(ball_texture, rect(0, 0, ball_texture.Width, ball_texture.Height),
+	rl.DrawTexturePro(ball_sprite.Texture, ball_sprite.Source,
 		rl.NewRectangle(ball.X, ball.Y, float32(DefaultBallRadius*2), float32(DefaultBallRadius*2)),
-		vec2(DefaultBallRadius, DefaultBallRadius), -(ball.Rotation+math.Pi/2)*rl.Rad2deg, rl.White)
+		vec2(DefaultBallRadius, DefaultBallRadius), -(ball.Rotation+math.Pi/2)*rl.Rad2deg, GetBallTint(ball.Type, rl.White))
 
 	var alpha int32 = 0
 	time := globalBoard.StateCount % 100
@@ -221,11 +289,10 @@ func (ball *Ball) DrawStandardPower(theBallImageId, theBlinkImageId TextureKey)
 
 	ball_color := globalDarkBallColors[ball.Type]
 	ball_color.A = uint8(alpha)
-	rl.DrawTexturePro(blink_texture, rect(0, 0, blink_texture.Width, blink_texture.Height),
-		rl.NewRectangle(ball.X, ball.Y, float32(blink_texture.Width), float32(blink_texture.Height)),
-		vec2(blink_texture.Width/2, blink_texture.Height/2), -(ball.Rotation+math.Pi/2)*rl.Rad2deg, ball_color)
-
-	rl.EndBlendMode()
+	width, height := blink_sprite.Source.Width, blink_sprite.Source.Height
+	rl.DrawTexturePro(blink_sprite.Texture, blink_sprite.Source,
+		rl.NewRectangle(ball.X, ball.Y, width, height),
+		rl.NewVector2(float32(int32(width)/2), float32(int32(height)/2)), -(ball.Rotation+math.Pi/2)*rl.Rad2deg, ball_color)
 }
 
 func (ball *Ball) GetCollidesWithPrev(list []*Ball) bool {
@@ -236,20 +303,24 @@ func (ball *Ball) GetCollidesWithPrev(list []*Ball) bool {
 	return false
 }
 
+// GetListIndex returns the position of the ball in list, or -1 if the ball is not in it.
+func (ball *Ball) GetListIndex(list []*Ball) int {
+	if ball.ListIndex < 0 || ball.ListIndex >= len(list) || list[ball.ListIndex] != ball {
+		return -1
+	}
+	return ball.ListIndex
+}
+
 func (ball *Ball) GetNextBall(mustCollide bool, list []*Ball) *Ball {
-	if len(list) == 0 {
-		return nil
-	} else {
-		index := slices.Index(list, ball)
-		if index == -1 || index == len(list)-1 {
-			return nil
-		}
-		index++
-		if !mustCollide || ball.CollidesWithNext {
-			return list[index]
-		}
+	index := ball.GetListIndex(list)
+	if index == -1 || index == len(list)-1 {
 		return nil
 	}
+	index++
+	if !mustCollide || ball.CollidesWithNext {
+		return list[index]
+	}
+	return nil
 }
 
 func (ball *Ball) GetPowerTypeWussy() PowerType {
@@ -260,10 +331,7 @@ func (ball *Ball) GetPowerTypeWussy() PowerType {
 }
 
 func (ball *Ball) GetPrevBall(mustCollide bool, list []*Ball) *Ball {
-	if len(list) == 0 {
-		return nil
-	}
-	index := slices.Index(list, ball)
+	index := ball.GetListIndex(list)
 	if index < 1 {
 		return nil
 	}
@@ -280,6 +348,25 @@ func (ball *Ball) GetPrevBall(mustCollide bool, list []*Ball) *Ball {
 
 func (ball *Ball) InsertInList(theList *[]*Ball, index int) {
 	*theList = slices.Insert(*theList, index, ball)
+	UpdateListIndices(*theList, index)
+}
+
+// RemoveFromList takes the ball out of its list, the balls behind it move up one place.
+func (ball *Ball) RemoveFromList(theList *[]*Ball) {
+	index := ball.GetListIndex(*theList)
+	if index == -1 {
+		return
+	}
+	*theList = slices.Delete(*theList, index, index+1)
+	ball.ListIndex = -1
+	UpdateListIndices(*theList, index)
+}
+
+// UpdateListIndices renumbers the balls from theFirst on, after balls were inserted or removed in front of them.
+func UpdateListIndices(theList []*Ball, theFirst int) {
+	for i := theFirst; i < len(theList); i++ {
+		theList[i].ListIndex = i
+	}
 }
 
 func (ball *Ball) Intersects(p1, v1 rl.Vector3, t *float32) bool {
@@ -299,7 +386,7 @@ func (ball *Ball) Intersects(p1, v1 rl.Vector3, t *float32) bool {
 }
 
 func (ball *Ball) RandomizeFrame() {
-	ball.StartFrame = rand.Int31n(50)
+	ball.StartFrame = globalRand.Int31n(50)
 }
 
 func (ball *Ball) SetCollidesWithPrev(collidesWithPrev bool, list []*Ball) {
@@ -310,8 +397,7 @@ func (ball *Ball) SetCollidesWithPrev(collidesWithPrev bool, list []*Ball) {
 }
 
 func (ball *Ball) SetFrame(theFrame int32) {
-	the_texture := gTextures[TextureKey(ball.Type+int32(Texture_BlueBall))]
-	num_rows := the_texture.Height / the_texture.Width
+	num_rows := gTextureLayouts[globalColorTextures[ColorTexture_Ball][ball.Type]].Rows
 	ball.StartFrame = num_rows - int32(float32(theFrame)+ball.WayPoint)%num_rows
 }
 
@@ -356,21 +442,24 @@ func (ball *Ball) StartClearCount(inTunnel bool) {
 	ball.ClearCount = 1
 
 	if !inTunnel {
-		if ball.Particles == nil {
+		if num := len(gParticlePool); ball.Particles == nil && num > 0 {
+			ball.Particles = gParticlePool[num-1]
+			gParticlePool = gParticlePool[:num-1]
+		} else if ball.Particles == nil {
 			ball.Particles = new([60]Particle)
 		}
 		for i := range 60 {
 			ptcl := &(*ball.Particles)[i]
-			angle := float64(rand.Int31n(360)) * rl.Deg2rad
-			speed := float32(rand.Int31n(500)) / 500
+			angle := float64(globalRand.Int31n(360)) * rl.Deg2rad
+			speed := float32(globalRand.Int31n(500)) / 500
 			ptcl.VX = float32(math.Sin(angle)) * speed
 			ptcl.VY = float32(math.Cos(angle)) * speed
 
-			rnd := float32(rand.Int31n(30))
+			rnd := float32(globalRand.Int31n(30))
 			ptcl.X = rnd*ptcl.VX + ball.X
 			ptcl.Y = rnd*ptcl.VY + ball.Y
 			ptcl.Size = 1
-			if rand.Int31n(10) < 2 {
+			if globalRand.Int31n(10) < 2 {
 				ptcl.Size++
 			}
 		}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// checkListIndices checks that every ball knows its place in theList and finds its neighbours through it.
+func checkListIndices(t *testing.T, theList []*Ball) {
+	t.Helper()
+	for i, ball := range theList {
+		if ball.ListIndex != i || ball.GetListIndex(theList) != i {
+			t.Errorf("ball %d: ListIndex %d, GetListIndex %d", i, ball.ListIndex, ball.GetListIndex(theList))
+		}
+		var want_prev, want_next *Ball = nil, nil
+		if i > 0 {
+			want_prev = theList[i-1]
+		}
+		if i < len(theList)-1 {
+			want_next = theList[i+1]
+		}
+		if prev := ball.GetPrevBall(false, theList); prev != want_prev {
+			t.Errorf("ball %d: wrong previous ball", i)
+		}
+		if next := ball.GetNextBall(false, theList); next != want_next {
+			t.Errorf("ball %d: wrong next ball", i)
+		}
+	}
+}
+
+func TestListIndex(t *testing.T) {
+	const num_balls int = 6
+	tests := []struct {
+		Name string
+		// Change changes the chain of theCurve, which holds theBalls, and returns the chain expected afterwards.
+		Change func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball
+	}{
+		{"insert at front", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			ball := NewBall()
+			ball.InsertInList(&theCurve.BallList, 0)
+			return slices.Insert(theBalls, 0, ball)
+		}},
+		{"insert in the middle", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			ball := NewBall()
+			ball.InsertInList(&theCurve.BallList, 3)
+			return slices.Insert(theBalls, 3, ball)
+		}},
+		{"insert at the back", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			ball := NewBall()
+			ball.InsertInList(&theCurve.BallList, len(theBalls))
+			return append(theBalls, ball)
+		}},
+		{"remove from the middle", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			ball := theBalls[2]
+			ball.RemoveFromList(&theCurve.BallList)
+			if ball.ListIndex != -1 || ball.GetListIndex(theCurve.BallList) != -1 {
+				t.Errorf("the removed ball still has ListIndex %d", ball.ListIndex)
+			}
+			return slices.Delete(theBalls, 2, 3)
+		}},
+		{"remove during UpdateSets", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			for _, ball := range theBalls[1:4] {
+				ball.ClearCount = 40
+			}
+			// a set can take more than one update to go, the list has to be right after every one
+			for range 3 {
+				theCurve.UpdateSets()
+				checkListIndices(t, theCurve.BallList)
+			}
+			return slices.Delete(theBalls, 1, 4)
+		}},
+		{"RemoveBallsAtFront", func(t *testing.T, theCurve *Curve, theBalls []*Ball) []*Ball {
+			theBalls[0].WayPoint = 0
+			theCurve.RemoveBallsAtFront()
+			if !slices.Contains(theCurve.PendingBalls, theBalls[0]) {
+				t.Error("the ball removed at the front is not pending again")
+			}
+			return theBalls[1:]
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			curve := &newBenchBoard(t, 1, num_balls).CurveList[0]
+			curve.StopAddingBalls = false
+			balls := slices.Clone(curve.BallList)
+			checkListIndices(t, curve.BallList)
+
+			want := test.Change(t, curve, balls)
+			if !slices.Equal(curve.BallList, want) {
+				t.Fatalf("got a chain of %d balls, want %d in the expected order", len(curve.BallList), len(want))
+			}
+			checkListIndices(t, curve.BallList)
+		})
+	}
+}
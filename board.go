@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"image/color"
 	"math"
@@ -14,6 +15,7 @@ type Board struct {
 	BallColorMap              map[int32]int32
 	BulletList                []*Bullet
 	CurveList                 []Curve
+	LevelScope                *LevelScope
 	BallDrawer                *BallDrawer
 	ParticleMgr               *ParticleMgr
 	SpriteMgr                 *SpriteMgr
@@ -47,6 +49,10 @@ type Board struct {
 	FlashCount                int32
 	LevelEndFrame             int32
 	NeedComboCount            []*Ball
+	ScoreTexts                TextList
+	LivesText                 string
+	LivesTextCount            int32
+	LevelNameText             string
 	LevelDesc                 *LevelDesc
 	LevelStats                GameStats
 	GameState                 GameState
@@ -60,6 +66,32 @@ type GameStats struct {
 	NumCombos                 int32
 	MaxCombo, MaxComboScore   int32
 	MaxInARow, MaxInARowScore int32
+	NumDangers                int32
+	NumRepeatSpawns           int32
+	NumSingleSpawns           int32
+	NumBallsSpawned           [len(globalBallColors)]int32
+	NumPowerUpsSpawned        [PowerType_Max]int32
+}
+
+func (stats *GameStats) Accumulate(theOther *GameStats) {
+	stats.TimePlayed += theOther.TimePlayed
+	stats.NumBallsCleared += theOther.NumBallsCleared
+	stats.NumGemsCleared += theOther.NumGemsCleared
+	stats.NumGaps += theOther.NumGaps
+	stats.NumCombos += theOther.NumCombos
+	stats.MaxCombo = max(stats.MaxCombo, theOther.MaxCombo)
+	stats.MaxComboScore = max(stats.MaxComboScore, theOther.MaxComboScore)
+	stats.MaxInARow = max(stats.MaxInARow, theOther.MaxInARow)
+	stats.MaxInARowScore = max(stats.MaxInARowScore, theOther.MaxInARowScore)
+	stats.NumDangers += theOther.NumDangers
+	stats.NumRepeatSpawns += theOther.NumRepeatSpawns
+	stats.NumSingleSpawns += theOther.NumSingleSpawns
+	for i := range stats.NumBallsSpawned {
+		stats.NumBallsSpawned[i] += theOther.NumBallsSpawned[i]
+	}
+	for i := range stats.NumPowerUpsSpawned {
+		stats.NumPowerUpsSpawned[i] += theOther.NumPowerUpsSpawned[i]
+	}
 }
 
 type GameState int32
@@ -72,6 +104,9 @@ const (
 	GameState_LevelBegin
 )
 
+// LevelNameFrames is how long the name of a level shows when it starts, it fades out over the last second.
+const LevelNameFrames int32 = 3 * TargetFPS
+
 func NewBoard() *Board {
 	tmp := &Board{
 		BallColorMap: make(map[int32]int32),
@@ -111,7 +146,6 @@ func (b *Board) AdvanceFreeBullet(index *int) {
 
 	for i := range b.CurveList {
 		if b.CurveList[i].CheckCollision(bullet) {
-			b.BulletList[*index] = nil
 			b.BulletList = slices.Delete(b.BulletList, *index, *index+1)
 			return
 		}
@@ -127,9 +161,10 @@ func (b *Board) AdvanceFreeBullet(index *int) {
 		*index++
 	} else {
 		b.ResetInARowBonus()
-		bullet = nil
-		b.BulletList[*index] = nil
 		b.BulletList = slices.Delete(b.BulletList, *index, *index+1)
+		bullet.BeforeDestroy()
+		ReleaseBullet(bullet)
+		bullet = nil
 	}
 }
 
@@ -169,6 +204,7 @@ func (b *Board) CheckReload() {
 func (b *Board) Draw() {
 	b.DrawPlaying()
 	b.DrawText()
+	b.DrawLevelName()
 	b.DrawOverlay()
 }
 
@@ -181,6 +217,18 @@ func (b *Board) DrawBullets() {
 	}
 }
 
+// DrawLevelName shows the translated name of the level while it starts.
+func (b *Board) DrawLevelName() {
+	if b.StateCount >= LevelNameFrames {
+		return
+	}
+	if b.LevelNameText == "" {
+		b.LevelNameText = GetLevelDisplayName(b.LevelDesc)
+	}
+	alpha := min(LevelNameFrames-b.StateCount, TargetFPS) * 255 / TargetFPS
+	gFonts[FontType_Title].DrawTextWrapped(b.LevelNameText, 40, GameHeight/3, GameWidth-80, TextAlign_Center, color.RGBA{255, 255, 255, uint8(alpha)})
+}
+
 func (b *Board) DrawOverlay() {
 	b.ParticleMgr.DrawTopMost()
 }
@@ -212,6 +260,8 @@ func (b *Board) DrawText() {
 	more_than_3 := false
 	var show_count int32 = 0
 
+	font := gFonts[FontType_Hud]
+	text_color := color.RGBA{255, 255, 0, 255}
 	rl.DrawRectangle(25, 3, 80, 23, color.RGBA{19, 50, 9, 255})
 	if (b.LivesBlinkCount & 0x10) == 0 {
 		lives := b.Lives - 1
@@ -220,15 +270,13 @@ func (b *Board) DrawText() {
 		}
 
 		if b.IsEndless {
-			text = "Survival"
-			text_width := rl.MeasureText(text, 16)
-			rl.DrawText(text, 64-text_width/2, 6, 16, color.RGBA{255, 255, 0, 255})
+			text = Tr("hud.survival")
+			font.DrawTextAligned(text, 64, 22, TextAlign_Center, text_color)
 			more_than_3, show_count = false, 0
 		} else {
 			if lives == 0 {
-				text = "Last Life"
-				text_width := rl.MeasureText(text, 16)
-				rl.DrawText(text, 64-text_width/2, 6, 16, color.RGBA{255, 255, 0, 255})
+				text = Tr("hud.last_life")
+				font.DrawTextAligned(text, 64, 22, TextAlign_Center, text_color)
 				more_than_3, show_count = false, 0
 			} else if lives <= 3 {
 				more_than_3, show_count = false, lives
@@ -244,15 +292,59 @@ func (b *Board) DrawText() {
 		}
 
 		if more_than_3 {
-			rl.DrawText(fmt.Sprintf("x%d", lives), frog_x+4, 6, 16, color.RGBA{255, 255, 0, 255})
+			if b.LivesText == "" || b.LivesTextCount != lives {
+				b.LivesText, b.LivesTextCount = Tr("hud.lives", lives), lives
+			}
+			font.DrawText(b.LivesText, frog_x+4, 22, text_color)
+		}
+	}
+}
+
+// ResetNeedComboCount empties the list but keeps its memory.
+func (b *Board) ResetNeedComboCount() {
+	clear(b.NeedComboCount)
+	b.NeedComboCount = b.NeedComboCount[:0]
+}
+
+func (b *Board) FireBullet() bool {
+	for i := range b.CurveList {
+		if !b.CurveList[i].CanFire() {
+			return false
 		}
 	}
+	if !b.Frog.StartFire(true) {
+		return false
+	}
+	b.SoundMgr.AddSound(Sound_FrogFire, 0, GetPanForX(float32(b.Frog.CenterX)), 0)
+	return true
 }
 
 func (b *Board) GetTickCount() uint32 {
 	return 10 * uint32(b.StateCount)
 }
 
+// GetTension rises from 0 to 1 as the farthest ball of any curve moves through the last half of its curve.
+func (b *Board) GetTension() float32 {
+	var tension float32 = 0
+	for i := range b.CurveList {
+		curve := &b.CurveList[i]
+		tension = max(tension, min(max(float32(curve.GetFarthestBallPercent()-50)/45, 0), 1))
+		if curve.InDanger {
+			tension = max(tension, 0.75)
+		}
+	}
+	return tension
+}
+
+func (b *Board) HasBallReachedHole() bool {
+	for i := range b.CurveList {
+		if b.CurveList[i].HasReachedHole() {
+			return true
+		}
+	}
+	return false
+}
+
 func (b *Board) IncScore(theInc int32, delayDisplay bool) {
 	if theInc <= 0 {
 		return
@@ -262,19 +354,31 @@ func (b *Board) IncScore(theInc int32, delayDisplay bool) {
 	if score/50000 < (score+theInc)/50000 && !b.IsEndless && !b.IsWinning {
 		b.Lives += (score+theInc)/50000 - score/50000
 		b.LivesBlinkCount = 150
-		rl.PlaySound(gSounds[Sound_ExtraLife])
-		b.SoundMgr.AddSound(gSounds[Sound_ExtraLife], 30, 0, 0)
-		b.SoundMgr.AddSound(gSounds[Sound_ExtraLife], 60, 0, 0)
+		b.SoundMgr.AddSound(Sound_ExtraLife, 0, 0, 0)
+		b.SoundMgr.AddSound(Sound_ExtraLife, 30, 0, 0)
+		b.SoundMgr.AddSound(Sound_ExtraLife, 60, 0, 0)
 	}
 	if !delayDisplay {
 		b.ScoreDisplay = b.Score
 	}
 }
 
-func (b *Board) PlayBallClick(theSound SoundKey) {
+func (b *Board) IsBoardCleared() bool {
+	if len(b.BulletList) != 0 {
+		return false
+	}
+	for i := range b.CurveList {
+		if len(b.CurveList[i].BallList) != 0 || len(b.CurveList[i].PendingBalls) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Board) PlayBallClick(theSound SoundKey, theX float32) {
 	tick := b.GetTickCount()
 	if tick-b.LastBallClickTick >= 250 {
-		rl.PlaySound(gSounds[theSound])
+		b.SoundMgr.AddSound(theSound, 0, GetPanForX(theX), 0)
 		b.LastBallClickTick = tick
 	}
 }
@@ -287,13 +391,143 @@ func (b *Board) ResetInARowBonus() {
 	b.NumClearsInARow, b.CurInARowBonus = 0, 0
 }
 
+// SetupLevel prepares the board for a level. Missing assets leave parts of the level empty,
+// they are returned together, named by the level id.
+// SetupLevel sets the board up for a level. Missing art is reported and left out, but a level cannot run
+// without its curves: when one fails to load the board keeps the level it had.
+func (b *Board) SetupLevel(theDesc *LevelDesc) error {
+	// The new level acquires its assets before the old one releases its own, so a restart reloads nothing.
+	scope := NewLevelScope(theDesc.Name)
+	curve_points := make([][]PathPoint, len(theDesc.CurveDescs))
+	var curve_errs []error
+	for i := range theDesc.CurveDescs {
+		path_points, err := scope.AcquireCurve(theDesc.CurveDescs[i].FilePath)
+		curve_points[i] = path_points
+		curve_errs = append(curve_errs, err)
+	}
+	if err := errors.Join(curve_errs...); err != nil {
+		scope.Release()
+		return fmt.Errorf("level %q: %w", theDesc.Name, err)
+	}
+	defer func() {
+		b.LevelScope.Release()
+		b.LevelScope = scope
+	}()
+
+	b.LevelDesc = theDesc
+	SelectLevelTheme(theDesc)
+	b.SpriteMgr = NewSpriteMgr()
+	b.SpriteMgr.InSpace = theDesc.IsInSpace
+	err := b.SpriteMgr.SetupLevel(theDesc, scope, GetBackgroundPath(theDesc))
+	b.ParticleMgr.Reset()
+
+	b.Frog.EmptyBullets()
+	b.BulletList = nil
+	clear(b.BallColorMap)
+	b.ResetNeedComboCount()
+	b.LevelStats = GameStats{}
+	b.HasReachedTarget, b.IsWinning = false, false
+	b.LevelEndFrame, b.FlashCount, b.BarBlinkCount = 0, 0, 0
+	b.CurBarSize, b.TargetBarSize = 0, 0
+	b.ResetInARowBonus()
+	b.DoAccuracy(false)
+	globalBallBlink = false
+
+	b.LevelBeginScore = b.Score
+	b.ScoreTarget = b.Score
+	if len(theDesc.CurveDescs) != 0 {
+		b.ScoreTarget += theDesc.CurveDescs[0].ScoreTarget
+	}
+
+	b.CurveList = make([]Curve, len(theDesc.CurveDescs))
+	for i := range b.CurveList {
+		b.CurveList[i] = Curve{Board: b, WayPointMgr: new(WayPointMgr), CurveIndex: int32(i)}
+		b.CurveList[i].SetupLevel(theDesc, b.SpriteMgr, int32(i), curve_points[i])
+	}
+	if err != nil {
+		return fmt.Errorf("level %q: %w", theDesc.Name, err)
+	}
+	return nil
+}
+
+// LoadLevel sets a level up and starts it. A level that could not be set up does not start,
+// the board goes on with the level it had.
+func (b *Board) LoadLevel(theDesc *LevelDesc) error {
+	err := b.SetupLevel(theDesc)
+	if b.LevelDesc == theDesc {
+		b.StartLevel()
+	}
+	return err
+}
+
+func GetBackgroundPath(theDesc *LevelDesc) string {
+	if theDesc.ImagePath == "" {
+		return ""
+	}
+	return "./levels/" + theDesc.Name + "/" + theDesc.ImagePath + ".jpg"
+}
+
+// ReloadLevel loads the art and the curves of the running level again, after their files changed.
+// The balls stay on the board, each keeps its position relative to the length of its curve.
+func (b *Board) ReloadLevel() error {
+	scope := NewLevelScope(b.LevelDesc.Name)
+	defer func() {
+		b.LevelScope.Release()
+		b.LevelScope = scope
+	}()
+
+	sprite_mgr := NewSpriteMgr()
+	sprite_mgr.InSpace = b.LevelDesc.IsInSpace
+	sprite_mgr.UpdateCnt = b.SpriteMgr.UpdateCnt
+	sprite_mgr.HoleFlashes = b.SpriteMgr.HoleFlashes
+	errs := []error{sprite_mgr.SetupLevel(b.LevelDesc, scope, GetBackgroundPath(b.LevelDesc))}
+	b.SpriteMgr = sprite_mgr
+	for i := range b.CurveList {
+		path_points, err := scope.AcquireCurve(b.LevelDesc.CurveDescs[i].FilePath)
+		if err != nil {
+			// the curve keeps the path it has
+			errs = append(errs, err)
+			continue
+		}
+		b.CurveList[i].ReloadPath(sprite_mgr, path_points)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("level %q: %w", b.LevelDesc.Name, err)
+	}
+	return nil
+}
+
+// ApplyLevelDesc swaps the description of the running level for an edited one. Speeds, colors and the
+// score target take effect right away. A level whose number of curves changed has to start over.
+func (b *Board) ApplyLevelDesc(theDesc *LevelDesc) error {
+	if len(theDesc.CurveDescs) != len(b.CurveList) {
+		return b.LoadLevel(theDesc)
+	}
+	b.LevelDesc = theDesc
+	b.Frog.FireVel = theDesc.FireSpeed
+	b.Frog.SetPos(theDesc.FrogX, theDesc.FrogY)
+	b.ScoreTarget = b.LevelBeginScore
+	if len(theDesc.CurveDescs) != 0 {
+		b.ScoreTarget += theDesc.CurveDescs[0].ScoreTarget
+	}
+	for i := range b.CurveList {
+		b.CurveList[i].LevelDesc = theDesc
+		b.CurveList[i].CurveDesc = &theDesc.CurveDescs[i]
+	}
+	return b.ReloadLevel()
+}
+
 func (b *Board) StartLevel() {
 	b.GameState = GameState_Playing
 	b.StateCount = 0
+	b.LevelNameText = ""
 	b.Frog.FireVel = b.LevelDesc.FireSpeed
 	b.Frog.SetPos(b.LevelDesc.FrogX, b.LevelDesc.FrogY)
-	b.SoundMgr.PlayLoop(LoopType_RollIn)
+	b.SoundMgr.PlayLoop(LoopType_RollIn, 0)
 	b.LevelBeginning = true
+	for i := range b.CurveList {
+		b.CurveList[i].StartLevel()
+	}
 }
 
 func (b *Board) Update() {
@@ -311,6 +545,28 @@ func (b *Board) Update() {
 	b.UpdateMiscStuff()
 }
 
+// UpdateLoops plays the rolling out loop while a chain is pushed back and the heart beat while balls are close to a hole.
+func (b *Board) UpdateLoops() {
+	rolling_back := false
+	for i := range b.CurveList {
+		if b.CurveList[i].BackwardCount > 0 && len(b.CurveList[i].BallList) != 0 {
+			rolling_back = true
+		}
+	}
+	if rolling_back {
+		b.SoundMgr.PlayLoop(LoopType_RollOut, 10)
+	} else {
+		b.SoundMgr.StopLoop(LoopType_RollOut, 50)
+	}
+
+	if tension := b.GetTension(); tension > 0 {
+		b.SoundMgr.PlayLoop(LoopType_Danger, 50)
+		b.SoundMgr.SetLoopVolume(LoopType_Danger, tension)
+	} else {
+		b.SoundMgr.StopLoop(LoopType_Danger, 100)
+	}
+}
+
 func (b *Board) UpdateBallColorMap(theBall *Ball, added bool) {
 	if added {
 		b.BallColorMap[theBall.Type]++
@@ -440,9 +696,10 @@ func (b *Board) UpdatePlaying() {
 		}
 		if !still_starting || b.StateCount > 500 {
 			b.LevelBeginning = false
-			b.SoundMgr.StopLoop(LoopType_RollIn)
+			b.SoundMgr.StopLoop(LoopType_RollIn, 50)
 		}
 	}
+	b.UpdateLoops()
 
 	if !b.HasReachedTarget && b.CurBarSize == 256 && b.Score >= b.ScoreTarget {
 		b.BarBlinkCount = 224
@@ -479,7 +736,9 @@ func (b *Board) UpdatePlaying() {
 	}
 
 	for i := range b.CurveList {
-		b.CurveList[i].UpdatePlaying()
+		if !b.CurveList[i].Frozen {
+			b.CurveList[i].UpdatePlaying()
+		}
 	}
 
 	if b.StateCount > 50 {
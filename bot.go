@@ -0,0 +1,171 @@
+package main
+
+import (
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type Bot struct {
+	Board       *Board
+	IsRandom    bool
+	SwapMargin  float32
+	ScratchBall Ball
+}
+
+type BotAction struct {
+	Angle      float32
+	Fire, Swap bool
+}
+
+type BotShot struct {
+	Angle float32
+	Score float32
+	Valid bool
+}
+
+func NewBot(theBoard *Board) *Bot {
+	return &Bot{Board: theBoard, SwapMargin: 50}
+}
+
+func (bot *Bot) Apply(theAction BotAction) {
+	frog := bot.Board.Frog
+	frog.SetAngle(theAction.Angle)
+	if theAction.Swap {
+		frog.SwapBullets(!globalHeadless)
+	}
+	if theAction.Fire {
+		bot.Board.FireBullet()
+	}
+}
+
+func (bot *Bot) FindBestShot(theType int32) BotShot {
+	best := BotShot{Score: -1}
+	frog := bot.Board.Frog
+	for i := range bot.Board.CurveList {
+		curve := &bot.Board.CurveList[i]
+		for k := range curve.BallList {
+			ball := curve.BallList[k]
+			if ball.ClearCount != 0 || ball.Bullet != nil || curve.WayPointMgr.InTunnel1(int(ball.WayPoint)) {
+				continue
+			}
+			x, y := bot.PredictPosition(curve, ball)
+			angle := frog.CalcAngleTo(x, y)
+			hit_curve, hit_ball, in_front := bot.TraceShot(angle)
+			if hit_ball == nil {
+				continue
+			}
+			score := bot.RateShot(hit_curve, hit_ball, in_front, theType)
+			if score > best.Score {
+				best = BotShot{angle, score, true}
+			}
+		}
+	}
+	return best
+}
+
+func (bot *Bot) Play() {
+	bot.Apply(bot.Think())
+}
+
+func (bot *Bot) PredictPosition(theCurve *Curve, theBall *Ball) (float32, float32) {
+	frog := bot.Board.Frog
+	dx, dy := theBall.X-float32(frog.CenterX), theBall.Y-float32(frog.CenterY)
+	fire_vel := max(frog.FireVel, 1)
+	// the tongue takes a few frames before the bullet leaves the frog
+	frames := float32(math.Sqrt(float64(dx*dx+dy*dy)))/fire_vel + 7
+
+	lead := theCurve.AdvanceSpeed * frames
+	if theCurve.StopTime > 0 || theCurve.BackwardCount > 0 {
+		lead = 0
+	}
+	theCurve.WayPointMgr.SetWayPoint(&bot.ScratchBall, theBall.WayPoint+lead)
+	return bot.ScratchBall.X, bot.ScratchBall.Y
+}
+
+func (bot *Bot) RateShot(theCurve *Curve, theHitBall *Ball, inFront bool, theType int32) float32 {
+	var neighbor *Ball
+	if inFront {
+		neighbor = theHitBall.GetNextBall(true, theCurve.BallList)
+	} else {
+		neighbor = theHitBall.GetPrevBall(true, theCurve.BallList)
+	}
+
+	var count int32 = 1
+	var target *Ball = nil
+	if theHitBall.Type == theType {
+		target = theHitBall
+	} else if neighbor != nil && neighbor.Type == theType {
+		target = neighbor
+	}
+	if target != nil {
+		count += theCurve.GetNumInARow(target, theType, nil, nil)
+	}
+
+	progress := theHitBall.WayPoint / float32(max(theCurve.GetCurveLength(), 1))
+	if count >= 3 {
+		score := 1000 + 10*float32(count) + 100*progress
+		if target.GetPowerTypeWussy() != PowerType_None {
+			score += 200
+		}
+		return score
+	}
+	if count == 2 {
+		return 100 + 50*progress
+	}
+	// nothing to match, so dump the ball as far away from the hole as possible
+	return 10 * (1 - progress)
+}
+
+func (bot *Bot) Think() BotAction {
+	frog := bot.Board.Frog
+	action := BotAction{Angle: frog.Angle}
+	if frog.State != FROGSTATE_NORMAL || frog.Bullet == nil {
+		return action
+	}
+	if bot.IsRandom {
+		action.Angle = globalRand.Float32() * 2 * math.Pi
+		action.Swap = globalRand.Int31n(10) == 0
+		action.Fire = !action.Swap
+		return action
+	}
+
+	best := bot.FindBestShot(frog.Bullet.Type)
+	if frog.NextBullet != nil && frog.NextBullet.Type != frog.Bullet.Type {
+		swapped := bot.FindBestShot(frog.NextBullet.Type)
+		if swapped.Valid && swapped.Score > best.Score+bot.SwapMargin {
+			action.Angle, action.Swap = swapped.Angle, true
+			return action
+		}
+	}
+	if best.Valid {
+		action.Angle, action.Fire = best.Angle, true
+	}
+	return action
+}
+
+func (bot *Bot) TraceShot(theAngle float32) (*Curve, *Ball, bool) {
+	frog := bot.Board.Frog
+	rad := float64(theAngle - math.Pi/2)
+	p1 := rl.NewVector3(float32(frog.CenterX), float32(frog.CenterY), 0)
+	v1 := rl.NewVector3(float32(math.Cos(rad)), -float32(math.Sin(rad)), 0)
+
+	var t float32 = 10000000
+	var hit_curve *Curve = nil
+	var hit_ball *Ball = nil
+	for i := range bot.Board.CurveList {
+		intersect_ball := bot.Board.CurveList[i].CheckBallIntersection(p1, v1, &t)
+		if intersect_ball != nil {
+			hit_curve, hit_ball = &bot.Board.CurveList[i], intersect_ball
+		}
+	}
+	if hit_ball == nil {
+		return nil, nil, false
+	}
+
+	hit_point := rl.Vector3Add(p1, rl.Vector3Scale(v1, t))
+	v := rl.NewVector3(hit_ball.X, hit_ball.Y, 0)
+	v2 := hit_curve.WayPointMgr.CalcPerpendicular(hit_ball.WayPoint)
+	in_front := rl.Vector3CrossProduct(rl.Vector3Subtract(hit_point, v), v2).Z < 0
+	return hit_curve, hit_ball, in_front
+}
@@ -11,7 +11,7 @@ type Bullet struct {
 	DestX, DestY               float32
 	HitPercent                 float32
 	MergeSpeed                 float32
-	GapInfos                   []*GapInfo
+	GapInfos                   []GapInfo
 	CurCurvePoint              []int32
 }
 
@@ -19,13 +19,30 @@ type GapInfo struct {
 	CurveIndex, Dist, Id int32
 }
 
+var gBulletPool []*Bullet
+
+// NewBullet takes a bullet from the pool, keeping the memory of its gap and curve point lists.
 func NewBullet() *Bullet {
-	tmp := &Bullet{}
-	tmp.Ball = *NewBall()
+	var tmp *Bullet = nil
+	if num := len(gBulletPool); num > 0 {
+		tmp = gBulletPool[num-1]
+		gBulletPool[num-1] = nil
+		gBulletPool = gBulletPool[:num-1]
+		gap_infos, cur_curve_point := tmp.GapInfos[:0], tmp.CurCurvePoint[:0]
+		*tmp = Bullet{GapInfos: gap_infos, CurCurvePoint: cur_curve_point}
+	} else {
+		tmp = &Bullet{}
+	}
+	tmp.Ball.Init()
 	tmp.MergeSpeed = 0.05
 	return tmp
 }
 
+// ReleaseBullet hands a bullet that is in no list anymore back to the pool.
+func ReleaseBullet(theBullet *Bullet) {
+	gBulletPool = append(gBulletPool, theBullet)
+}
+
 func (bullet *Bullet) BeforeDestroy() {
 	bullet.Ball.BeforeDestroy()
 	bullet.SetBallInfo(nil)
@@ -37,7 +54,7 @@ func (bullet *Bullet) AddGapInfo(theCurve, theDist, theBallId int32) bool {
 			return false
 		}
 	}
-	bullet.GapInfos = append(bullet.GapInfos, &GapInfo{theCurve, theDist, theBallId})
+	bullet.GapInfos = append(bullet.GapInfos, GapInfo{theCurve, theDist, theBallId})
 	return true
 }
 
@@ -104,7 +121,6 @@ func (bullet *Bullet) MergeFully() {
 func (bullet *Bullet) RemoveGapInfoForBall(theBallId int32) {
 	for i := 0; i != len(bullet.GapInfos); {
 		if bullet.GapInfos[i].Id == theBallId {
-			bullet.GapInfos[i] = nil
 			bullet.GapInfos = slices.Delete(bullet.GapInfos, i, i+1)
 		} else {
 			i++
@@ -118,6 +134,11 @@ func (bullet *Bullet) SetBallInfo(theBullet *Bullet) {
 	}
 }
 
+func (bullet *Bullet) SetNumCurves(theNumCurves int) {
+	bullet.CurCurvePoint = slices.Grow(bullet.CurCurvePoint[:0], theNumCurves)[:theNumCurves]
+	clear(bullet.CurCurvePoint)
+}
+
 func (bullet *Bullet) SetCurCurvePoint(theCurveNum, thePoint int32) {
 	bullet.CurCurvePoint[theCurveNum] = thePoint
 }
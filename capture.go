@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"log/slog"
+	"os"
+	"path"
+	"time"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const CaptureDir string = "./captures"
+
+type CaptureSettings struct {
+	GifSeconds int32   `json:"gif_seconds"`
+	GifFps     int32   `json:"gif_fps"`
+	GifScale   float32 `json:"gif_scale"`
+}
+
+// Recorder keeps the last GifSeconds of gameplay while recording and saves them as a gif when stopped.
+type Recorder struct {
+	Settings    *CaptureSettings
+	Display     *Display
+	Console     *Console
+	IsRecording bool
+	Frames      []*image.Paletted
+	NextFrame   int
+	FrameCount  int32
+	Encoded     chan CaptureResult
+	NumPending  int
+}
+
+// CaptureResult tells which file a background encode wrote and whether it failed.
+type CaptureResult struct {
+	FilePath string
+	Err      error
+}
+
+// globalGifPalette is the 6x6x6 web safe color cube, so pixels map to it without a nearest color search.
+var globalGifPalette color.Palette = func() color.Palette {
+	palette := make(color.Palette, 0, 216)
+	for r := range 6 {
+		for g := range 6 {
+			for b := range 6 {
+				palette = append(palette, color.RGBA{uint8(r * 51), uint8(g * 51), uint8(b * 51), 255})
+			}
+		}
+	}
+	return palette
+}()
+
+func NewRecorder(theSettings *CaptureSettings, theDisplay *Display, theConsole *Console) *Recorder {
+	return &Recorder{Settings: theSettings, Display: theDisplay, Console: theConsole, Encoded: make(chan CaptureResult, 4)}
+}
+
+func (recorder *Recorder) Update(theInput *InputMgr, theBoard *Board) {
+	if theInput.IsPressed(InputAction_Screenshot) {
+		recorder.report(SaveScreenshot(recorder.Display, GetCapturePath(theBoard, ".png")))
+	}
+	if theInput.IsPressed(InputAction_Record) {
+		if recorder.IsRecording {
+			recorder.Stop(GetCapturePath(theBoard, ".gif"))
+		} else {
+			recorder.Start()
+		}
+	}
+	if recorder.IsRecording {
+		recorder.CaptureFrame()
+	}
+	select {
+	case result := <-recorder.Encoded:
+		recorder.NumPending--
+		recorder.report(result.FilePath, result.Err)
+	default:
+	}
+}
+
+// Wait blocks until the recordings still being encoded are written, so quitting does not cut a gif short.
+func (recorder *Recorder) Wait() {
+	for ; recorder.NumPending > 0; recorder.NumPending-- {
+		result := <-recorder.Encoded
+		recorder.report(result.FilePath, result.Err)
+	}
+}
+
+func (recorder *Recorder) report(theFilePath string, theErr error) {
+	message := "saved " + theFilePath
+	if theErr != nil {
+		message = theErr.Error()
+		slog.Error("capture failed", "file", theFilePath, "err", theErr)
+	}
+	if recorder.Console != nil {
+		recorder.Console.Print("%s", message)
+	}
+}
+
+func (recorder *Recorder) getFrameStep() int32 {
+	return max(1, TargetFPS/max(1, recorder.Settings.GifFps))
+}
+
+func (recorder *Recorder) Start() {
+	num_frames := max(1, recorder.Settings.GifSeconds*TargetFPS/recorder.getFrameStep())
+	recorder.Frames = make([]*image.Paletted, num_frames)
+	recorder.NextFrame, recorder.FrameCount = 0, 0
+	recorder.IsRecording = true
+	recorder.Display.ShowRecording = true
+	if recorder.Console != nil {
+		recorder.Console.Print("recording the last %d seconds", recorder.Settings.GifSeconds)
+	}
+}
+
+// Stop ends the recording and encodes the kept frames in the background.
+func (recorder *Recorder) Stop(theFilePath string) {
+	recorder.IsRecording = false
+	recorder.Display.ShowRecording = false
+	anim := &gif.GIF{}
+	delay := int(100 * recorder.getFrameStep() / TargetFPS)
+	for i := range recorder.Frames {
+		frame := recorder.Frames[(recorder.NextFrame+i)%len(recorder.Frames)]
+		if frame != nil {
+			anim.Image = append(anim.Image, frame)
+			anim.Delay = append(anim.Delay, delay)
+		}
+	}
+	recorder.Frames = nil
+	if len(anim.Image) == 0 {
+		return
+	}
+	if recorder.NumPending == cap(recorder.Encoded) {
+		recorder.report(theFilePath, fmt.Errorf("%s: still saving %d recordings", theFilePath, recorder.NumPending))
+		return
+	}
+	recorder.NumPending++
+	go func() {
+		err := writeCaptureFile(theFilePath, func(f *os.File) error { return gif.EncodeAll(f, anim) })
+		recorder.Encoded <- CaptureResult{FilePath: theFilePath, Err: err}
+	}()
+}
+
+func (recorder *Recorder) CaptureFrame() {
+	recorder.FrameCount++
+	if recorder.FrameCount%recorder.getFrameStep() != 0 {
+		return
+	}
+	scale := min(1, max(0.1, recorder.Settings.GifScale))
+	width, height := int32(float32(GameWidth)*scale), int32(float32(GameHeight)*scale)
+	img := rl.LoadImageFromTexture(recorder.Display.Target.Texture)
+	rl.ImageFlipVertical(img)
+	rl.ImageResize(img, width, height)
+	colors := rl.LoadImageColors(img)
+
+	frame := image.NewPaletted(image.Rect(0, 0, int(width), int(height)), globalGifPalette)
+	for i, c := range colors {
+		frame.Pix[i] = uint8((int(c.R)+25)/51*36 + (int(c.G)+25)/51*6 + (int(c.B)+25)/51)
+	}
+	rl.UnloadImageColors(colors)
+	rl.UnloadImage(img)
+
+	recorder.Frames[recorder.NextFrame] = frame
+	recorder.NextFrame = (recorder.NextFrame + 1) % len(recorder.Frames)
+}
+
+// SaveScreenshot writes the game image at its native resolution.
+func SaveScreenshot(theDisplay *Display, theFilePath string) (string, error) {
+	img := rl.LoadImageFromTexture(theDisplay.Target.Texture)
+	rl.ImageFlipVertical(img)
+	colors := rl.LoadImageColors(img)
+	shot := image.NewRGBA(image.Rect(0, 0, int(img.Width), int(img.Height)))
+	for i, c := range colors {
+		shot.Pix[i*4], shot.Pix[i*4+1], shot.Pix[i*4+2], shot.Pix[i*4+3] = c.R, c.G, c.B, 255
+	}
+	rl.UnloadImageColors(colors)
+	rl.UnloadImage(img)
+	return theFilePath, writeCaptureFile(theFilePath, func(f *os.File) error { return png.Encode(f, shot) })
+}
+
+// InitHiddenWindow opens a hidden window, so the render command and the draw tests can use the real textures.
+func InitHiddenWindow(theTitle string) error {
+	rl.SetConfigFlags(rl.FlagWindowHidden)
+	rl.SetTraceLogLevel(rl.LogWarning)
+	rl.InitWindow(GameWidth, GameHeight, theTitle)
+	return errors.Join(InitGlobalTextures(), InitFonts(), InitLocalization())
+}
+
+// RenderBoard updates the board theFrames times, then draws it once and writes the game image to a png.
+func RenderBoard(theBoard *Board, theFrames int32, theFilePath string) error {
+	display := NewDisplay(&DisplaySettings{Width: GameWidth, Height: GameHeight})
+	defer display.Destroy()
+	for range theFrames {
+		theBoard.Update()
+	}
+	display.Begin()
+	theBoard.Draw()
+	display.End()
+	_, err := SaveScreenshot(display, theFilePath)
+	return err
+}
+
+// GetCapturePath names a capture after the current level and the time to the millisecond,
+// e.g. captures/spiral_20240131-184502.123.png, so captures taken within a second do not overwrite each other.
+func GetCapturePath(theBoard *Board, theExt string) string {
+	level_name := "board"
+	if theBoard.LevelDesc != nil {
+		level_name = theBoard.LevelDesc.Name
+	}
+	return path.Join(CaptureDir, level_name+"_"+time.Now().Format("20060102-150405.000")+theExt)
+}
+
+func writeCaptureFile(theFilePath string, theEncode func(*os.File) error) error {
+	if err := os.MkdirAll(path.Dir(theFilePath), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(theFilePath)
+	if err != nil {
+		return err
+	}
+	if err := theEncode(file); err != nil {
+		file.Close()
+		return fmt.Errorf("%s: %w", theFilePath, err)
+	}
+	return file.Close()
+}
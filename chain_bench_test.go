@@ -0,0 +1,247 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"testing"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const BenchChainLength int = 1000
+
+var benchParser LevelParser
+
+var checkDraw = flag.Bool("draw", false, "also check that drawing does not allocate, in a hidden window")
+
+// TestMain loads the built-in assets headless. With -draw it opens a hidden window instead, so the boards
+// can also be drawn with the real textures.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	benchParser = NewLevelParser()
+	init_assets := InitHeadless
+	if *checkDraw {
+		init_assets = func() error { return InitHiddenWindow("test") }
+	}
+	if err := init_assets(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := benchParser.ParseLevels(LevelsPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if *checkDraw {
+		rl.CloseWindow()
+	}
+	os.Exit(code)
+}
+
+// makeBenchPath builds a path that zigzags over the screen, long enough to hold very long chains.
+func makeBenchPath(theNumPoints int, theOffset float32) []WayPoint {
+	const row_width, row_height int = 600, 6
+	way_points := make([]WayPoint, theNumPoints)
+	for i := range way_points {
+		row, col := i/row_width, i%row_width
+		if row%2 == 1 {
+			col = row_width - 1 - col
+		}
+		way_points[i].X = float32(20 + col)
+		way_points[i].Y = theOffset + float32((row*row_height)%int(GameHeight-80))
+	}
+	return way_points
+}
+
+// newBenchBoard sets up the first level with at least theNumCurves curves and puts a chain of
+// theNumBalls balls on each curve. New balls are not added.
+func newBenchBoard(tb testing.TB, theNumCurves, theNumBalls int) *Board {
+	var desc *LevelDesc = nil
+	for _, id := range slices.Sorted(maps.Keys(benchParser.GraphicsMap)) {
+		if level, found := benchParser.MakeLevel(id, ""); found && len(level.CurveDescs) >= theNumCurves {
+			desc = level
+			break
+		}
+	}
+	if desc == nil {
+		tb.Fatalf("no level with %d curves", theNumCurves)
+	}
+	desc.CurveDescs = desc.CurveDescs[:theNumCurves]
+
+	board := NewBoard()
+	globalBoard = board
+	tb.Cleanup(func() { board.LevelScope.Release() })
+	if err := board.SetupLevel(desc); err != nil {
+		tb.Fatal(err)
+	}
+	board.StartLevel()
+	board.LevelBeginning = false
+
+	spacing := float32(DefaultBallRadius * 2)
+	for i := range board.CurveList {
+		curve := &board.CurveList[i]
+		curve.WayPointMgr.WayPoints = makeBenchPath(theNumBalls*int(spacing)+2000, float32(40+i*2))
+		curve.DangerPoint = curve.WayPointMgr.GetNumPoints() - curve.CurveDesc.DangerDistance
+		curve.BuildWayPointGrid()
+		curve.SetStopAddingBalls(true)
+		for k := range theNumBalls {
+			ball := NewBall()
+			ball.Type = int32(k/2) % max(curve.CurveDesc.NumColors, 1)
+			curve.WayPointMgr.SetWayPoint(ball, 100+float32(k)*spacing)
+			ball.SetRotation(curve.WayPointMgr.GetRotationForPoint(int(ball.WayPoint)), true)
+			ball.CollidesWithNext = k != theNumBalls-1
+			curve.BallList = append(curve.BallList, ball)
+		}
+		UpdateListIndices(curve.BallList, 0)
+	}
+	return board
+}
+
+// benchmarkChains runs theFrame on boards with a long chain on one, two and three curves.
+func benchmarkChains(b *testing.B, theFrame func(*Board)) {
+	for num_curves := 1; num_curves <= 3; num_curves++ {
+		b.Run(fmt.Sprintf("%dx%d", num_curves, BenchChainLength), func(b *testing.B) {
+			board := newBenchBoard(b, num_curves, BenchChainLength)
+			b.ReportAllocs()
+			for b.Loop() {
+				theFrame(board)
+			}
+		})
+	}
+}
+
+func BenchmarkChainNeighbours(b *testing.B) {
+	benchmarkChains(b, func(theBoard *Board) {
+		for i := range theBoard.CurveList {
+			list := theBoard.CurveList[i].BallList
+			for _, ball := range list {
+				ball.GetNextBall(false, list)
+				ball.GetPrevBall(true, list)
+				ball.UpdateCollisionInfo(5, list)
+			}
+		}
+	})
+}
+
+func BenchmarkChainNumInARow(b *testing.B) {
+	benchmarkChains(b, func(theBoard *Board) {
+		for i := range theBoard.CurveList {
+			curve := &theBoard.CurveList[i]
+			for _, ball := range curve.BallList {
+				curve.GetNumInARow(ball, ball.Type, nil, nil)
+			}
+		}
+	})
+}
+
+// updateFrozen updates a board whose chains are stopped, so every frame goes over the full chains.
+func updateFrozen(theBoard *Board) {
+	for i := range theBoard.CurveList {
+		theBoard.CurveList[i].StopTime = 100
+	}
+	theBoard.Update()
+}
+
+func BenchmarkChainUpdate(b *testing.B) {
+	benchmarkChains(b, updateFrozen)
+}
+
+func BenchmarkChainGapShot(b *testing.B) {
+	benchmarkChains(b, func(theBoard *Board) {
+		bullet := NewBullet()
+		bullet.SetNumCurves(len(theBoard.CurveList))
+		for y := range GameHeight / 8 {
+			for x := range GameWidth / 8 {
+				bullet.X, bullet.Y = float32(x*8), float32(y*8)
+				for i := range theBoard.CurveList {
+					theBoard.CurveList[i].CheckGapShot(bullet)
+				}
+			}
+		}
+		ReleaseBullet(bullet)
+	})
+}
+
+// botGame lets the bot play the level the game starts with, so the chain moves, bullets are fired into it
+// and sets are cleared.
+type botGame struct {
+	Board    *Board
+	Bot      *Bot
+	NumShots int
+}
+
+func newBotGame(t *testing.T) *botGame {
+	desc, found := benchParser.MakeLevel(FirstLevelId, "")
+	if !found {
+		t.Fatalf("unknown level %q", FirstLevelId)
+	}
+	SeedRandom(1)
+	board := NewBoard()
+	globalBoard = board
+	t.Cleanup(func() { board.LevelScope.Release() })
+	if err := board.SetupLevel(desc); err != nil {
+		t.Fatal(err)
+	}
+	board.StartLevel()
+	return &botGame{Board: board, Bot: NewBot(board)}
+}
+
+func (game *botGame) Update() {
+	action := game.Bot.Think()
+	if action.Fire {
+		game.NumShots++
+	}
+	game.Bot.Apply(action)
+	game.Board.Update()
+}
+
+// checkAllocs runs theFrame until the pools and lists have grown. After that, ten seconds of frames must
+// not allocate, and the bot has to keep playing the level in them.
+func (game *botGame) checkAllocs(t *testing.T, theFrame func()) {
+	for range 10 * TargetFPS {
+		theFrame()
+	}
+	const num_frames = 10 * TargetFPS
+	game.NumShots = 0
+	num_cleared := game.Board.LevelStats.NumBallsCleared
+	// the first run warms up too, only the second one is counted
+	allocs := testing.AllocsPerRun(1, func() {
+		for range num_frames {
+			theFrame()
+		}
+	})
+	num_cleared = game.Board.LevelStats.NumBallsCleared - num_cleared
+	if game.Board.HasBallReachedHole() {
+		t.Fatal("the bot lost the level, the frames measured did not play it")
+	}
+	if game.NumShots == 0 || num_cleared == 0 {
+		t.Fatalf("%d shots and %d balls cleared, the frames measured did not play the level", game.NumShots, num_cleared)
+	}
+	if allocs > 0 {
+		t.Errorf("%.0f allocations in %d frames", allocs, num_frames)
+	}
+}
+
+func TestUpdateAllocs(t *testing.T) {
+	game := newBotGame(t)
+	game.checkAllocs(t, game.Update)
+}
+
+// TestDrawAllocs draws every frame into a render texture, it needs a window and only runs with -draw.
+func TestDrawAllocs(t *testing.T) {
+	if !*checkDraw {
+		t.Skip("drawing needs a window, run with -draw")
+	}
+	game := newBotGame(t)
+	target := rl.LoadRenderTexture(GameWidth, GameHeight)
+	defer rl.UnloadRenderTexture(target)
+	game.checkAllocs(t, func() {
+		game.Update()
+		rl.BeginTextureMode(target)
+		game.Board.Draw()
+		rl.EndTextureMode()
+	})
+}
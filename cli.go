@@ -0,0 +1,271 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Command is a subcommand of the binary, without one the game is played.
+type Command struct {
+	Name, Summary string
+	Run           func(theArgs []string) error
+}
+
+func GetCommands() []Command {
+	return []Command{
+		{"play", "play the game, the default command", CmdPlay},
+		{"validate", "load every asset headless and list the problems", CmdValidate},
+		{"simulate", "let the bot play levels headless and print a balance or difficulty report", CmdSimulate},
+		{"render", "draw a level into a png in a hidden window", CmdRender},
+	}
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "usage: %s [command] [flags]\n\ncommands:\n", os.Args[0])
+	for _, command := range GetCommands() {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.Name, command.Summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun '%s <command> -h' for the flags of a command\n", os.Args[0])
+}
+
+// RunCommand runs the command named by the first argument and returns the exit code.
+// Arguments starting with a flag go to the play command.
+func RunCommand(theArgs []string) int {
+	name := "play"
+	if len(theArgs) > 0 && !strings.HasPrefix(theArgs[0], "-") {
+		name, theArgs = theArgs[0], theArgs[1:]
+	}
+	if name == "help" {
+		printUsage()
+		return 0
+	}
+	defer globalVFS.Close()
+	for _, command := range GetCommands() {
+		if command.Name == name {
+			if err := command.Run(theArgs); err != nil {
+				LogErrors(slog.LevelError, name+" failed", err)
+				return 1
+			}
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	printUsage()
+	return 2
+}
+
+// CommonFlags are understood by every command.
+type CommonFlags struct {
+	DataPaths []string
+	Verbose   bool
+}
+
+func NewFlagSet(theName string, theCommon *CommonFlags) *flag.FlagSet {
+	set := flag.NewFlagSet(theName, flag.ExitOnError)
+	set.BoolVar(&theCommon.Verbose, "verbose", false, "also log debug messages")
+	set.Func("data", "data directory or zip mod pack whose files override the built-in assets, can be repeated, later ones win", func(theValue string) error {
+		theCommon.DataPaths = append(theCommon.DataPaths, theValue)
+		return nil
+	})
+	return set
+}
+
+// Apply sets the logger up and mounts the data paths.
+func (common *CommonFlags) Apply() error {
+	InitLogger(common.Verbose)
+	for _, data_path := range common.DataPaths {
+		if err := globalVFS.Mount(data_path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LevelFlags pick the level to start with, either by graphics and settings id or by its place in the stage progression.
+type LevelFlags struct {
+	GraphicsId, SettingsId string
+	Stage, Level           int
+}
+
+func (level_flags *LevelFlags) Register(theSet *flag.FlagSet) {
+	theSet.StringVar(&level_flags.GraphicsId, "graphics", "", "start with this graphics id")
+	theSet.StringVar(&level_flags.SettingsId, "settings", "", "apply this settings id to the graphics")
+	theSet.IntVar(&level_flags.Stage, "stage", 0, "start with a level of this stage of the progression, instead of -graphics")
+	theSet.IntVar(&level_flags.Level, "level", 1, "the level of -stage, counting from 1")
+}
+
+// Start starts the picked level on theSession. Without a pick an adventure starts at the beginning
+// of the stage progression and the other modes play the first level.
+func (level_flags *LevelFlags) Start(theSession *Session) error {
+	if level_flags.Stage != 0 {
+		for i, stage := range theSession.Parser.StageProgression {
+			if int(stage.Stage) == level_flags.Stage {
+				return theSession.StartStage(i, level_flags.Level-1)
+			}
+		}
+		return fmt.Errorf("%s: unknown stage %d", LevelsPath, level_flags.Stage)
+	}
+	if level_flags.GraphicsId != "" {
+		return theSession.StartLevel(level_flags.GraphicsId, level_flags.SettingsId)
+	}
+	if theSession.Mode == GameMode_Adventure && len(theSession.Parser.StageProgression) != 0 {
+		return theSession.StartStage(0, 0)
+	}
+	return theSession.StartLevel(FirstLevelId, "")
+}
+
+// GameOptions are the command line choices of the play command. Fullscreen and Mute are nil unless given,
+// the values given only hold for this run and are not saved to the settings.
+type GameOptions struct {
+	Level            LevelFlags
+	Mode             GameMode
+	Seed             int64
+	Width, Height    int32
+	Fullscreen, Mute *bool
+	Watch            bool
+}
+
+func CmdPlay(theArgs []string) error {
+	var common CommonFlags
+	var options GameOptions
+	set := NewFlagSet("play", &common)
+	options.Level.Register(set)
+	mode := set.String("mode", "adventure", "game mode: adventure, gauntlet or practice")
+	set.Int64Var(&options.Seed, "seed", 0, "random seed, 0 picks one from the clock")
+	width := set.Int("width", 0, "window width, needs -height")
+	height := set.Int("height", 0, "window height, needs -width")
+	fullscreen := set.Bool("fullscreen", false, "start fullscreen, -fullscreen=false starts in a window")
+	mute := set.Bool("mute", false, "start with the sound muted")
+	set.BoolVar(&options.Watch, "watch", false, "reload changed levels, fonts, images and sounds while playing, reads the current directory when no -data is given")
+	set.Parse(theArgs)
+
+	var err error
+	if options.Mode, err = ParseGameMode(*mode); err != nil {
+		return err
+	}
+	if (*width > 0) != (*height > 0) {
+		return fmt.Errorf("-width and -height go together")
+	}
+	options.Width, options.Height = int32(*width), int32(*height)
+	set.Visit(func(theFlag *flag.Flag) {
+		switch theFlag.Name {
+		case "fullscreen":
+			options.Fullscreen = fullscreen
+		case "mute":
+			options.Mute = mute
+		}
+	})
+	if options.Watch && len(common.DataPaths) == 0 {
+		common.DataPaths = append(common.DataPaths, ".")
+	}
+	if err := common.Apply(); err != nil {
+		return err
+	}
+	return RunGame(&options)
+}
+
+func CmdValidate(theArgs []string) error {
+	var common CommonFlags
+	set := NewFlagSet("validate", &common)
+	set.Parse(theArgs)
+	if err := common.Apply(); err != nil {
+		return err
+	}
+	if err := ValidateAssets(); err != nil {
+		return fmt.Errorf("%d problems found: %w", len(SplitErrors(err)), err)
+	}
+	fmt.Println("all assets are valid")
+	return nil
+}
+
+func CmdSimulate(theArgs []string) error {
+	var common CommonFlags
+	set := NewFlagSet("simulate", &common)
+	progression := set.Bool("progression", false, "play every level of the stage progression and write a difficulty report")
+	graphics := set.String("graphics", "", "only simulate this graphics id")
+	settings := set.String("settings", "", "only simulate this settings id")
+	games := set.Int("games", 3, "number of bot games per graphics/settings combination")
+	lives := set.Int("lives", 3, "lives per bot game")
+	minutes := set.Int("minutes", 15, "maximum game time of a bot game in minutes")
+	seed := set.Int64("seed", 1, "random seed of the first bot game, following games use seed+1, seed+2, ...")
+	shooter := set.String("shooter", "bot", "shooter used by the simulation: bot or random")
+	report_format := set.String("format", "csv", "progression report format: csv or json")
+	report_out := set.String("out", "", "write the progression report to this file instead of stdout")
+	set.Parse(theArgs)
+
+	if *shooter != "bot" && *shooter != "random" {
+		return fmt.Errorf("unknown shooter %q", *shooter)
+	}
+	if err := common.Apply(); err != nil {
+		return err
+	}
+	level_parser := NewLevelParser()
+	if err := InitHeadless(); err != nil {
+		return err
+	}
+	if err := level_parser.ParseLevels(LevelsPath); err != nil {
+		return err
+	}
+	options := SimOptions{
+		NumGames:  int32(*games),
+		Lives:     int32(*lives),
+		MaxFrames: int32(*minutes) * 60 * TargetFPS,
+		Seed:      *seed,
+		IsRandom:  *shooter == "random",
+	}
+	if !*progression {
+		return RunBalanceTest(&level_parser, *graphics, *settings, &options, os.Stdout)
+	}
+	output := os.Stdout
+	if *report_out != "" {
+		var err error
+		if output, err = os.Create(*report_out); err != nil {
+			return err
+		}
+		defer output.Close()
+	}
+	return RunProgressionReport(&level_parser, &options, *report_format, output)
+}
+
+func CmdRender(theArgs []string) error {
+	var common CommonFlags
+	var level_flags LevelFlags
+	set := NewFlagSet("render", &common)
+	level_flags.Register(set)
+	frames := set.Int("frames", int(3*TargetFPS), "number of updates before the level is drawn")
+	seed := set.Int64("seed", 1, "random seed of the ball colors")
+	output := set.String("out", "", "png to write, by default a file named after the level in "+CaptureDir)
+	set.Parse(theArgs)
+	if err := common.Apply(); err != nil {
+		return err
+	}
+
+	defer rl.CloseWindow()
+	if err := InitHiddenWindow("render"); err != nil {
+		return err
+	}
+	level_parser := NewLevelParser()
+	if err := level_parser.ParseLevels(LevelsPath); err != nil {
+		return err
+	}
+	SeedRandom(*seed)
+	globalBoard = NewBoard()
+	defer func() { globalBoard.LevelScope.Release() }()
+	if err := level_flags.Start(NewSession(GameMode_Practice, globalBoard, &level_parser)); err != nil {
+		return err
+	}
+	file_path := *output
+	if file_path == "" {
+		file_path = GetCapturePath(globalBoard, ".png")
+	}
+	if err := RenderBoard(globalBoard, int32(*frames), file_path); err != nil {
+		return err
+	}
+	fmt.Println(file_path)
+	return nil
+}
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+const MaxConsoleLines int = 200
+
+type Console struct {
+	Board        *Board
+	LevelParser  *LevelParser
+	InputMgr     *InputMgr
+	Display      *Display
+	IsOpen       bool
+	Paused       bool
+	AutoPlay     bool
+	StepCount    int32
+	Input        string
+	Lines        []string
+	History      []string
+	HistoryIndex int
+	Commands     map[string]ConsoleCommand
+}
+
+type ConsoleCommand struct {
+	Usage  string
+	Invoke func(console *Console, args []string) error
+}
+
+func NewConsole(theBoard *Board, theParser *LevelParser) *Console {
+	console := &Console{Board: theBoard, LevelParser: theParser}
+	console.Commands = map[string]ConsoleCommand{
+		"help":       {"help", (*Console).CmdHelp},
+		"bind":       {"bind <rotate_left|rotate_right|fire|swap|mute> <input>...", (*Console).CmdBind},
+		"bot":        {"bot <on|off>", (*Console).CmdBot},
+		"palette":    {"palette <name>", (*Console).CmdPalette},
+		"powerup":    {"powerup <curve> <ball> <bomb|slow|accuracy|backwards>", (*Console).CmdPowerUp},
+		"scale":      {"scale <integer|smooth>", (*Console).CmdScale},
+		"score":      {"score <value>", (*Console).CmdScore},
+		"language":   {"language <code>", (*Console).CmdLanguage},
+		"lives":      {"lives <value>", (*Console).CmdLives},
+		"mute":       {"mute <on|off>", (*Console).CmdMute},
+		"nextball":   {"nextball <color> [power]", (*Console).CmdNextBall},
+		"stopadding": {"stopadding <curve|all> <on|off>", (*Console).CmdStopAdding},
+		"level":      {"level <graphics> [settings]", (*Console).CmdLevel},
+		"freeze":     {"freeze <curve|all> <on|off>", (*Console).CmdFreeze},
+		"pause":      {"pause", (*Console).CmdPause},
+		"resume":     {"resume", (*Console).CmdResume},
+		"step":       {"step [frames]", (*Console).CmdStep},
+		"symbols":    {"symbols <on|off>", (*Console).CmdSymbols},
+		"theme":      {"theme <name|default>", (*Console).CmdTheme},
+		"volume":     {"volume <master|music|sfx|ui> <0-100>", (*Console).CmdVolume},
+	}
+	return console
+}
+
+func (console *Console) Draw() {
+	if !console.IsOpen {
+		return
+	}
+	var line_height int32 = 12
+	height := GameHeight / 2
+	rl.DrawRectangle(0, 0, GameWidth, height, color.RGBA{0, 0, 0, 200})
+	rl.DrawLine(0, height, GameWidth, height, color.RGBA{255, 255, 0, 255})
+
+	y := height - 2*line_height - 4
+	for i := len(console.Lines) - 1; i >= 0 && y >= 0; i-- {
+		rl.DrawText(console.Lines[i], 6, y, 10, color.RGBA{200, 200, 200, 255})
+		y -= line_height
+	}
+
+	cursor := ""
+	if (console.Board.StateCount/30)%2 == 0 || console.Paused {
+		cursor = "_"
+	}
+	rl.DrawText("> "+console.Input+cursor, 6, height-line_height-4, 10, color.RGBA{255, 255, 0, 255})
+}
+
+func (console *Console) Execute(theLine string) {
+	console.Print("> %s", theLine)
+	args := strings.Fields(theLine)
+	if len(args) == 0 {
+		return
+	}
+	console.History = append(console.History, theLine)
+	console.HistoryIndex = len(console.History)
+
+	command, found := console.Commands[strings.ToLower(args[0])]
+	if !found {
+		console.Print("unknown command %q, try help", args[0])
+		return
+	}
+	if err := command.Invoke(console, args[1:]); err != nil {
+		console.Print("%v", err)
+		console.Print("usage: %s", command.Usage)
+	}
+}
+
+func (console *Console) Print(theFormat string, args ...any) {
+	console.Lines = append(console.Lines, fmt.Sprintf(theFormat, args...))
+	if len(console.Lines) > MaxConsoleLines {
+		console.Lines = slices.Delete(console.Lines, 0, len(console.Lines)-MaxConsoleLines)
+	}
+}
+
+func (console *Console) ShouldUpdateBoard() bool {
+	if !console.Paused {
+		return true
+	}
+	if console.StepCount > 0 {
+		console.StepCount--
+		return true
+	}
+	return false
+}
+
+func (console *Console) Update() {
+	if rl.IsKeyPressed(rl.KeyGrave) {
+		console.IsOpen = !console.IsOpen
+		for rl.GetCharPressed() != 0 {
+		}
+		return
+	}
+	if !console.IsOpen {
+		return
+	}
+
+	for char := rl.GetCharPressed(); char != 0; char = rl.GetCharPressed() {
+		if char >= 32 && char < 127 && char != '`' {
+			console.Input += string(rune(char))
+		}
+	}
+
+	if (rl.IsKeyPressed(rl.KeyBackspace) || rl.IsKeyPressedRepeat(rl.KeyBackspace)) && len(console.Input) > 0 {
+		console.Input = console.Input[:len(console.Input)-1]
+	}
+	if rl.IsKeyPressed(rl.KeyUp) && console.HistoryIndex > 0 {
+		console.HistoryIndex--
+		console.Input = console.History[console.HistoryIndex]
+	}
+	if rl.IsKeyPressed(rl.KeyDown) && console.HistoryIndex < len(console.History) {
+		console.HistoryIndex++
+		console.Input = ""
+		if console.HistoryIndex < len(console.History) {
+			console.Input = console.History[console.HistoryIndex]
+		}
+	}
+	if (rl.IsKeyPressed(rl.KeyEnter) || rl.IsKeyPressed(rl.KeyKpEnter)) && !IsAltDown() {
+		line := console.Input
+		console.Input = ""
+		console.Execute(line)
+	}
+}
+
+func (console *Console) CmdBind(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("expected at least 2 arguments")
+	}
+	if console.InputMgr == nil {
+		return fmt.Errorf("no input manager")
+	}
+	action := InputAction(strings.ToLower(args[0]))
+	if _, found := console.InputMgr.Settings.Bindings[action]; !found {
+		return fmt.Errorf("unknown action %q", args[0])
+	}
+	bindings := make([]InputBinding, len(args)-1)
+	for i, name := range args[1:] {
+		binding, err := ParseInputBinding(name)
+		if err != nil {
+			return err
+		}
+		bindings[i] = binding
+	}
+	console.InputMgr.Bindings[action] = bindings
+	console.InputMgr.Settings.Bindings[action] = slices.Clone(args[1:])
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("%s bound to %s", action, strings.Join(args[1:], " "))
+	return nil
+}
+
+func (console *Console) CmdBot(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument")
+	}
+	auto_play, err := parseOnOff(args[0])
+	if err != nil {
+		return err
+	}
+	console.AutoPlay = auto_play
+	console.Print("bot: %v", auto_play)
+	return nil
+}
+
+func (console *Console) CmdFreeze(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected 2 arguments")
+	}
+	curves, err := console.parseCurves(args[0])
+	if err != nil {
+		return err
+	}
+	frozen, err := parseOnOff(args[1])
+	if err != nil {
+		return err
+	}
+	for _, curve := range curves {
+		curve.Frozen = frozen
+	}
+	console.Print("freeze %s: %v", args[0], frozen)
+	return nil
+}
+
+func (console *Console) CmdHelp(args []string) error {
+	for _, name := range slices.Sorted(maps.Keys(console.Commands)) {
+		console.Print("  %s", console.Commands[name].Usage)
+	}
+	return nil
+}
+
+func (console *Console) CmdLevel(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected 1 or 2 arguments")
+	}
+	settings_id := ""
+	if len(args) == 2 {
+		settings_id = args[1]
+	}
+	desc, found := console.LevelParser.MakeLevel(args[0], settings_id)
+	if !found {
+		return fmt.Errorf("unknown graphics %q or settings %q", args[0], settings_id)
+	}
+	if err := console.Board.LoadLevel(desc); err != nil {
+		LogErrors(slog.LevelWarn, "level incomplete", err)
+		return err
+	}
+	console.Print("loaded %s %s: %s", args[0], settings_id, GetLevelDisplayName(desc))
+	return nil
+}
+
+func (console *Console) CmdLanguage(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument, one of %s", strings.Join(GetLanguageNames(), ", "))
+	}
+	if !slices.Contains(GetLanguageNames(), args[0]) {
+		return fmt.Errorf("unknown language %q", args[0])
+	}
+	if err := ApplyLanguage(args[0]); err != nil {
+		return err
+	}
+	globalSettings.Language = args[0]
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("language: %s (%s)", args[0], globalCatalog.Name)
+	return nil
+}
+
+func (console *Console) CmdLives(args []string) error {
+	value, err := parseSingleInt(args)
+	if err != nil {
+		return err
+	}
+	console.Board.Lives = value
+	console.Print("lives = %d", value)
+	return nil
+}
+
+func (console *Console) CmdMute(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument")
+	}
+	muted, err := parseOnOff(args[0])
+	if err != nil {
+		return err
+	}
+	globalSettings.Audio.Muted = muted
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("mute: %v", muted)
+	return nil
+}
+
+func (console *Console) CmdNextBall(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("expected 1 or 2 arguments")
+	}
+	the_type, err := parseBallColor(args[0])
+	if err != nil {
+		return err
+	}
+	power := PowerType_Max
+	if len(args) == 2 {
+		if power, err = parsePowerType(args[1]); err != nil {
+			return err
+		}
+	}
+	console.Board.Frog.ForcedType, console.Board.Frog.ForcedPower = the_type, power
+	console.Print("next bullet forced to %s", args[0])
+	return nil
+}
+
+func (console *Console) CmdPalette(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument, one of %s", strings.Join(GetBallPaletteNames(), ", "))
+	}
+	if err := ApplyBallPalette(args[0]); err != nil {
+		return err
+	}
+	globalSettings.Accessibility.BallPalette = args[0]
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("palette: %s", args[0])
+	return nil
+}
+
+func (console *Console) CmdTheme(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument, default or one of %s", strings.Join(GetThemeNames(), ", "))
+	}
+	name := args[0]
+	if name == "default" {
+		name = ""
+	} else if !slices.Contains(GetThemeNames(), name) {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	globalSettings.Theme = name
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	if console.Board.LevelDesc != nil && console.Board.LevelDesc.Theme != "" {
+		console.Print("theme: %s, the level keeps its own theme %s", args[0], console.Board.LevelDesc.Theme)
+		return nil
+	}
+	if err := ApplyTheme(name); err != nil {
+		return err
+	}
+	console.Print("theme: %s", args[0])
+	return nil
+}
+
+func (console *Console) CmdPause(args []string) error {
+	console.Paused = true
+	console.StepCount = 0
+	console.Print("paused at frame %d", console.Board.StateCount)
+	return nil
+}
+
+func (console *Console) CmdPowerUp(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected 3 arguments")
+	}
+	curve_index, err := strconv.Atoi(args[0])
+	if err != nil || curve_index < 0 || curve_index >= len(console.Board.CurveList) {
+		return fmt.Errorf("invalid curve %q", args[0])
+	}
+	ball_index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid ball %q", args[1])
+	}
+	power, err := parsePowerType(args[2])
+	if err != nil {
+		return err
+	}
+	if !console.Board.CurveList[curve_index].AddPowerUpToBall(ball_index, power) {
+		return fmt.Errorf("ball %d on curve %d can not take a power-up", ball_index, curve_index)
+	}
+	console.Print("power-up %s added to ball %d on curve %d", args[2], ball_index, curve_index)
+	return nil
+}
+
+func (console *Console) CmdResume(args []string) error {
+	console.Paused = false
+	console.StepCount = 0
+	console.Print("resumed")
+	return nil
+}
+
+func (console *Console) CmdScale(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument")
+	}
+	mode := ScaleMode(strings.ToLower(args[0]))
+	if mode != ScaleMode_Integer && mode != ScaleMode_Smooth {
+		return fmt.Errorf("invalid scale mode %q", args[0])
+	}
+	if console.Display == nil {
+		return fmt.Errorf("no display")
+	}
+	console.Display.SetScaleMode(mode)
+	console.Print("scale: %s", mode)
+	return nil
+}
+
+func (console *Console) CmdScore(args []string) error {
+	value, err := parseSingleInt(args)
+	if err != nil {
+		return err
+	}
+	console.Board.Score, console.Board.ScoreDisplay = value, value
+	console.Print("score = %d", value)
+	return nil
+}
+
+func (console *Console) CmdStep(args []string) error {
+	var frames int32 = 1
+	if len(args) != 0 {
+		value, err := parseSingleInt(args)
+		if err != nil {
+			return err
+		}
+		frames = value
+	}
+	console.Paused = true
+	console.StepCount += frames
+	console.Print("stepping %d frame(s)", frames)
+	return nil
+}
+
+func (console *Console) CmdStopAdding(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected 2 arguments")
+	}
+	curves, err := console.parseCurves(args[0])
+	if err != nil {
+		return err
+	}
+	stop, err := parseOnOff(args[1])
+	if err != nil {
+		return err
+	}
+	for _, curve := range curves {
+		curve.SetStopAddingBalls(stop)
+	}
+	console.Print("stopadding %s: %v", args[0], stop)
+	return nil
+}
+
+func (console *Console) CmdSymbols(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expected 1 argument")
+	}
+	symbols, err := parseOnOff(args[0])
+	if err != nil {
+		return err
+	}
+	globalSettings.Accessibility.BallSymbols = symbols
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("ball symbols: %v", symbols)
+	return nil
+}
+
+func (console *Console) CmdVolume(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("expected 2 arguments")
+	}
+	bus, err := ParseAudioBus(strings.ToLower(args[0]))
+	if err != nil {
+		return err
+	}
+	value, err := parseSingleInt(args[1:])
+	if err != nil {
+		return err
+	}
+	SetBusVolume(bus, float32(value)/100)
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		return err
+	}
+	console.Print("%s volume = %d", globalAudioBusNames[bus], value)
+	return nil
+}
+
+func (console *Console) parseCurves(theArg string) ([]*Curve, error) {
+	curves := make([]*Curve, 0, len(console.Board.CurveList))
+	if theArg == "all" {
+		for i := range console.Board.CurveList {
+			curves = append(curves, &console.Board.CurveList[i])
+		}
+		return curves, nil
+	}
+	index, err := strconv.Atoi(theArg)
+	if err != nil || index < 0 || index >= len(console.Board.CurveList) {
+		return nil, fmt.Errorf("invalid curve %q", theArg)
+	}
+	return append(curves, &console.Board.CurveList[index]), nil
+}
+
+func parseBallColor(theArg string) (int32, error) {
+	if the_type := slices.Index(globalBallColorNames[:], strings.ToLower(theArg)); the_type >= 0 {
+		return int32(the_type), nil
+	}
+	value, err := strconv.Atoi(theArg)
+	if err != nil || value < 0 || value >= len(globalBallColors) {
+		return 0, fmt.Errorf("invalid color %q", theArg)
+	}
+	return int32(value), nil
+}
+
+func parseOnOff(theArg string) (bool, error) {
+	switch strings.ToLower(theArg) {
+	case "on", "1", "true":
+		return true, nil
+	case "off", "0", "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected on or off, got %q", theArg)
+}
+
+func parsePowerType(theArg string) (PowerType, error) {
+	if power := slices.Index(globalPowerTypeNames[:], strings.ToLower(theArg)); power >= 0 {
+		return PowerType(power), nil
+	}
+	return PowerType_Max, fmt.Errorf("invalid power-up %q", theArg)
+}
+
+func parseSingleInt(args []string) (int32, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected 1 argument")
+	}
+	value, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", args[0])
+	}
+	return int32(value), nil
+}
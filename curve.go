@@ -1,37 +1,58 @@
 package main
 
 import (
-	"fmt"
 	"image/color"
 	"math"
-	"math/rand"
 	"slices"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
+// BallDrawer sorts balls by priority. Its lists keep their memory between frames.
 type BallDrawer struct {
-	NumBalls, NumShadows [MaxPriority]int32
-	Balls, Shadows       [MaxPriority][1024]*Ball
+	Balls, Shadows [MaxPriority][]*Ball
 }
 
 func (drawer *BallDrawer) Draw(theSpriteMgr *SpriteMgr, theParticleMgr *ParticleMgr) {
 	for i := range MaxPriority {
 		theSpriteMgr.DrawSprites(i)
 		theParticleMgr.Draw(i)
-		for k := range drawer.NumShadows[i] {
-			drawer.Shadows[i][k].DrawShadow()
+		// Each pass draws from one texture in one blend mode, the shadows and bodies come from the ball atlas.
+		for _, ball := range drawer.Shadows[i] {
+			ball.DrawShadow()
 		}
-		for k := range drawer.NumBalls[i] {
-			drawer.Balls[i][k].Draw()
+		for _, ball := range drawer.Balls[i] {
+			ball.DrawBody()
+		}
+		for _, ball := range drawer.Balls[i] {
+			ball.DrawParticles()
+		}
+		blending := false
+		for _, ball := range drawer.Balls[i] {
+			if !ball.HasLights() {
+				continue
+			}
+			if !blending {
+				rl.BeginBlendMode(rl.BlendAdditive)
+				blending = true
+			}
+			ball.DrawLights()
+		}
+		if blending {
+			rl.EndBlendMode()
+		}
+		for _, ball := range drawer.Balls[i] {
+			ball.DrawSymbol()
 		}
 	}
 }
 
 func (drawer *BallDrawer) Reset() {
 	for i := range MaxPriority {
-		drawer.NumBalls[i] = 0
-		drawer.NumShadows[i] = 0
+		clear(drawer.Balls[i])
+		clear(drawer.Shadows[i])
+		drawer.Balls[i] = drawer.Balls[i][:0]
+		drawer.Shadows[i] = drawer.Shadows[i][:0]
 	}
 }
 
@@ -61,6 +82,14 @@ type Curve struct {
 	HadPowerUp              bool
 	StopAddingBalls         bool
 	InDanger                bool
+	Frozen                  bool
+	SpawnRunLength          int32
+	WayPointGrid            SpatialGrid[int32]
+	BallGrid                SpatialGrid[*Ball]
+	BallGridFrame           int32
+	BallGridDirty           bool
+	GridBalls               []*Ball
+	GridWayPoints           []int32
 }
 
 var globalGotPowerUp [PowerType_Max]bool = [PowerType_Max]bool{false, false, false, false}
@@ -68,16 +97,17 @@ var globalGotPowerUp [PowerType_Max]bool = [PowerType_Max]bool{false, false, fal
 func (curve *Curve) ActivateBomb(theBall *Ball) {
 	color := globalBallColors[theBall.Type]
 	x, y := int32(theBall.X), int32(theBall.Y)
+	curve.Board.SoundMgr.AddSound(Sound_BallDestroyed5, 0, GetPanForX(theBall.X), -7)
 	curve.Board.ParticleMgr.AddExplosion(x, y, 0, color, 5)
-	v19 := gTextures[Texture_Explosion].Width / 3
+	v19 := int32(GetSprite(Texture_Explosion).Source.Width) / 3
 
 	var a6 int32 = 7
 	for i := v19; i < 100; i += v19 {
 		var v21 float32 = 0
 		for v21 < math.Pi*2 {
 			curve.Board.ParticleMgr.AddExplosion(
-				x+(rand.Int31n(21)-10)+int32(math.Sin(float64(v21))*float64(i)),
-				y+(rand.Int31n(21)-10)+int32(math.Cos(float64(v21))*float64(i)),
+				x+(globalRand.Int31n(21)-10)+int32(math.Sin(float64(v21))*float64(i)),
+				y+(globalRand.Int31n(21)-10)+int32(math.Cos(float64(v21))*float64(i)),
 				0, color, a6,
 			)
 			v21 += float32(v19) / float32(i)
@@ -136,6 +166,7 @@ func (curve *Curve) AddBall() {
 	curve.Board.UpdateBallColorMap(ball, true)
 
 	ball.InsertInList(&curve.BallList, 0)
+	curve.BallGridDirty = true
 	ball.UpdateCollisionInfo(5+int32(curve.AdvanceSpeed), curve.BallList)
 	ball.NeedCheckCollision = true
 	ball.SetRotation(curve.WayPointMgr.GetRotationForPoint(int(ball.WayPoint)), true)
@@ -159,33 +190,53 @@ func (curve *Curve) AddPendingBall() {
 	} else if len(curve.BallList) != 0 {
 		prev_color = curve.BallList[0].Type
 	} else {
-		prev_color = rand.Int31n(num_colors)
+		prev_color = globalRand.Int31n(num_colors)
 	}
 
 	if prev_color >= num_colors {
-		prev_color = rand.Int31n(num_colors)
+		prev_color = globalRand.Int31n(num_colors)
 	}
 
 	max_single := curve.CurveDesc.MaxSingle
-	if rand.Int31n(100) <= curve.CurveDesc.BallRepeat {
+	if globalRand.Int31n(100) <= curve.CurveDesc.BallRepeat {
 		new_color = prev_color
 	} else if max_single < 10 && curve.GetNumPendingSingles(1) == 1 && (max_single == 0 || curve.GetNumPendingSingles(10) > max_single) {
 		new_color = prev_color
 	} else {
 		for new_color == prev_color {
-			new_color = rand.Int31n(num_colors)
+			new_color = globalRand.Int31n(num_colors)
 		}
 	}
 	ball.Type = new_color
 	curve.PendingBalls = append(curve.PendingBalls, ball)
+
+	stats := &curve.Board.LevelStats
+	stats.NumBallsSpawned[new_color]++
+	if new_color == prev_color {
+		stats.NumRepeatSpawns++
+		curve.SpawnRunLength++
+	} else {
+		if curve.SpawnRunLength == 1 {
+			stats.NumSingleSpawns++
+		}
+		curve.SpawnRunLength = 1
+	}
+}
+
+func (curve *Curve) AddPowerUp(thePower PowerType) bool {
+	return curve.AddPowerUpToBall(globalRand.Intn(len(curve.BallList)), thePower)
 }
 
-func (curve *Curve) AddPowerUp(thePower PowerType) {
-	ball_idx := rand.Intn(len(curve.BallList))
-	ball := curve.BallList[ball_idx]
+func (curve *Curve) AddPowerUpToBall(theIndex int, thePower PowerType) bool {
+	if theIndex < 0 || theIndex >= len(curve.BallList) {
+		return false
+	}
+	ball := curve.BallList[theIndex]
 	if ball.PowerType == PowerType_None && ball.DestPowerType == PowerType_None {
 		ball.SetPowerType(thePower, true)
+		return true
 	}
+	return false
 }
 
 func (curve *Curve) AdvanceBackwardBalls() {
@@ -226,7 +277,7 @@ func (curve *Curve) AdvanceBackwardBalls() {
 				if next_ball.WayPoint > way_off_next {
 					next_ball.CollidesWithNext = true
 					collided = true
-					curve.Board.PlayBallClick(Sound_BallClick1)
+					curve.Board.PlayBallClick(Sound_BallClick1, next_ball.X)
 					backwards_speed = next_ball.WayPoint - way_off_next
 					next_ball.WayPoint = way_off_next
 				} else {
@@ -300,7 +351,7 @@ func (curve *Curve) AdvanceBalls() {
 			curve.WayPointMgr.SetWayPoint(next_ball, way_point+float32(DefaultBallRadius*2))
 			if !ball.CollidesWithNext {
 				ball.CollidesWithNext = true
-				curve.Board.PlayBallClick(Sound_BallClick1)
+				curve.Board.PlayBallClick(Sound_BallClick1, ball.X)
 			}
 			ball.NeedCheckCollision = false
 		}
@@ -326,7 +377,11 @@ func (curve *Curve) AdvanceBalls() {
 			curve.PathLightEndFrame = frame + curve.DrawPathSparkles(curve.FirstChainEnd, 0, false)
 		}
 	}
-	curve.InDanger = int32(curve.BallList[len(curve.BallList)-1].WayPoint) >= curve.DangerPoint
+	in_danger := int32(curve.BallList[len(curve.BallList)-1].WayPoint) >= curve.DangerPoint
+	if in_danger && !curve.InDanger {
+		curve.Board.LevelStats.NumDangers++
+	}
+	curve.InDanger = in_danger
 }
 
 func (curve *Curve) AdvanceBullets() {
@@ -338,6 +393,7 @@ func (curve *Curve) AdvanceBullets() {
 
 func (curve *Curve) AdvanceMergingBullet(index *int) {
 	bul := curve.BulletList[*index]
+	curve.BallGridDirty = true
 	bul.CheckSetHitBallToPrevBall(curve.BallList)
 	hit_ball := bul.HitBall
 	curve.WayPointMgr.SetWayPoint(&bul.Ball, hit_ball.WayPoint)
@@ -364,7 +420,7 @@ func (curve *Curve) AdvanceMergingBullet(index *int) {
 	}
 
 	if bul.HitPercent >= 1 {
-		ball_iter := slices.Index(curve.BallList, hit_ball)
+		ball_iter := hit_ball.GetListIndex(curve.BallList)
 		if bul.HitInFront {
 			ball_iter++
 		}
@@ -381,10 +437,9 @@ func (curve *Curve) AdvanceMergingBullet(index *int) {
 		min_gap_dist, num_gaps := bul.GetMinGapDist(), int32(len(bul.GapInfos))
 
 		bul.BeforeDestroy()
-		bul = nil
-
-		curve.BulletList[*index] = nil
 		curve.BulletList = slices.Delete(curve.BulletList, *index, *index+1)
+		ReleaseBullet(bul)
+		bul = nil
 		curve.TotalBalls++
 
 		prev_ball, next_ball := new_ball.GetPrevBall(false, curve.BallList), new_ball.GetNextBall(false, curve.BallList)
@@ -478,13 +533,18 @@ func (curve *Curve) CheckCollision(theBullet *Bullet) bool {
 		}
 	}
 
+	// Only balls in the cells around the bullet can touch it, they are checked in chain order.
+	curve.UpdateBallGrid()
+	curve.GridBalls = curve.BallGrid.Query(theBullet.X, theBullet.Y, curve.GridBalls[:0])
+	slices.SortFunc(curve.GridBalls, func(a, b *Ball) int { return a.ListIndex - b.ListIndex })
+
 	ball_index := 0
 	for ball_index = 0; ; ball_index++ {
-		if ball_index == len(curve.BallList) {
+		if ball_index == len(curve.GridBalls) {
 			return false
 		}
 
-		ball = curve.BallList[ball_index]
+		ball = curve.GridBalls[ball_index]
 		if ball.CollidesWithPhysically(&theBullet.Ball, 0) && ball.Bullet == nil && ball.ClearCount == 0 {
 			prev_ball := ball.GetPrevBall(true, curve.BallList)
 			if prev_ball == nil || prev_ball.Bullet == nil {
@@ -503,7 +563,7 @@ func (curve *Curve) CheckCollision(theBullet *Bullet) bool {
 		}
 	}
 
-	if ball_index != len(curve.BallList) {
+	if ball_index != len(curve.GridBalls) {
 		theBullet.SetHitBall(ball, flag)
 		theBullet.MergeSpeed = curve.CurveDesc.MergeSpeed
 
@@ -513,7 +573,7 @@ func (curve *Curve) CheckCollision(theBullet *Bullet) bool {
 		} else if next_ball2 != nil {
 			theBullet.RemoveGapInfoForBall(next_ball2.Id)
 		}
-		rl.PlaySound(gSounds[Sound_BallClick2])
+		curve.Board.SoundMgr.AddSound(Sound_BallClick2, 0, GetPanForX(theBullet.X), 0)
 		curve.BulletList = append(curve.BulletList, theBullet)
 		return true
 	}
@@ -536,28 +596,34 @@ func (curve *Curve) CheckGapShot(theBullet *Bullet) bool {
 		theBullet.SetCurCurvePoint(curve.CurveIndex, 0)
 	}
 
-	for i := int32(1); i < num_way_points; i += bul_diameter {
-		way_point := &curve.WayPointMgr.WayPoints[i]
-		if !way_point.InTunnel && bul_diameter_sq > (way_point.Y-bul_y)*(way_point.Y-bul_y)+(way_point.X-bul_x)*(way_point.X-bul_x) {
-			theBullet.SetCurCurvePoint(curve.CurveIndex, i)
-			for k := range curve.BallList {
-				ball := curve.BallList[k]
-				if int32(ball.WayPoint) > i {
-					prev_ball := ball.GetPrevBall(false, curve.BallList)
-					if prev_ball == nil {
-						return false
-					}
-					ball_dist := int32(ball.WayPoint - prev_ball.WayPoint)
-					if ball_dist <= 0 {
-						return false
-					}
-					return theBullet.AddGapInfo(curve.CurveIndex, ball_dist, ball.Id)
-				}
-			}
-			return false
+	// The first waypoint close to the bullet is searched among the ones indexed in the cells around it.
+	var i int32 = -1
+	curve.GridWayPoints = curve.WayPointGrid.Query(bul_x, bul_y, curve.GridWayPoints[:0])
+	for _, k := range curve.GridWayPoints {
+		way_point := &curve.WayPointMgr.WayPoints[k]
+		if (i == -1 || k < i) && bul_diameter_sq > (way_point.Y-bul_y)*(way_point.Y-bul_y)+(way_point.X-bul_x)*(way_point.X-bul_x) {
+			i = k
 		}
 	}
+	if i == -1 {
+		return false
+	}
 
+	theBullet.SetCurCurvePoint(curve.CurveIndex, i)
+	for k := range curve.BallList {
+		ball := curve.BallList[k]
+		if int32(ball.WayPoint) > i {
+			prev_ball := ball.GetPrevBall(false, curve.BallList)
+			if prev_ball == nil {
+				return false
+			}
+			ball_dist := int32(ball.WayPoint - prev_ball.WayPoint)
+			if ball_dist <= 0 {
+				return false
+			}
+			return theBullet.AddGapInfo(curve.CurveIndex, ball_dist, ball.Id)
+		}
+	}
 	return false
 }
 
@@ -577,7 +643,7 @@ func (curve *Curve) CheckSet(theBall *Ball) bool {
 	curve.Board.ClearedYSum = 0
 	curve.Board.CurComboCount = combo_count
 	curve.Board.CurComboScore = theBall.ComboScore
-	curve.Board.NeedComboCount = make([]*Ball, 0)
+	curve.Board.ResetNeedComboCount()
 
 	for i := range PowerType_Max {
 		globalGotPowerUp[i] = false
@@ -620,28 +686,25 @@ func (curve *Curve) CheckSet(theBall *Ball) bool {
 		ball := curve.Board.NeedComboCount[iter_index]
 		ball.ComboScore, ball.ComboCount = curve.Board.CurComboScore, combo_count
 	}
-	curve.Board.NeedComboCount = make([]*Ball, 0)
+	curve.Board.ResetNeedComboCount()
 
 	if !curve.HadPowerUp {
-		var destroy_sound rl.Sound
+		var destroy_sound SoundKey
 		switch combo_count {
 		case 0:
-			destroy_sound = gSounds[Sound_BallDestroyed1]
+			destroy_sound = Sound_BallDestroyed1
 		case 1:
-			destroy_sound = gSounds[Sound_BallDestroyed2]
+			destroy_sound = Sound_BallDestroyed2
 		case 2:
-			destroy_sound = gSounds[Sound_BallDestroyed3]
+			destroy_sound = Sound_BallDestroyed3
 		case 3:
-			destroy_sound = gSounds[Sound_BallDestroyed4]
+			destroy_sound = Sound_BallDestroyed4
 		default:
-			destroy_sound = gSounds[Sound_BallDestroyed5]
+			destroy_sound = Sound_BallDestroyed5
 		}
-		rl.PlaySound(destroy_sound)
-
-		combo_sound := gSounds[Sound_Combo]
-		rl.SetSoundPitch(combo_sound, float32(math.Pow(1.0594630943592952645618252949463, float64(2*combo_count))))
-		rl.SetSoundVolume(combo_sound, min(1.0, float32(combo_count)*0.2+0.4))
-		rl.PlaySound(combo_sound)
+		pan := GetPanForX(float32(curve.Board.ClearedXSum / max(curve.Board.NumCleared, 1)))
+		curve.Board.SoundMgr.AddSound(destroy_sound, 0, pan, 0)
+		curve.Board.SoundMgr.PlaySample(SoundDesc{Sound_Combo, min(1.0, float32(combo_count)*0.2+0.4), pan, float32(2 * combo_count)})
 	}
 
 	curve.Board.CurComboScore, curve.Board.CurComboCount = 0, 0
@@ -695,11 +758,11 @@ func (curve *Curve) DeleteBullet(theBullet *Bullet) {
 		return
 	}
 	found_index := slices.Index(curve.BulletList, theBullet)
+	theBullet.BeforeDestroy()
 	if found_index > -1 {
-		curve.BulletList[found_index] = nil
 		curve.BulletList = slices.Delete(curve.BulletList, found_index, found_index+1)
+		ReleaseBullet(theBullet)
 	}
-	theBullet.BeforeDestroy()
 	theBullet = nil
 }
 
@@ -708,8 +771,10 @@ func (curve *Curve) DoScoring(theBall *Ball, theNumBalls, theComboCount, theGapB
 		return
 	}
 
-	text_list := make([]string, 0)
+	texts := &curve.Board.ScoreTexts
+	texts.Reset()
 	num_points := 100*theComboCount + 10*theNumBalls + theGapBonus
+	pan := GetPanForX(float32(curve.Board.ClearedXSum / theNumBalls))
 	in_a_row := false
 	var row_bonus int32 = 0
 
@@ -730,42 +795,40 @@ func (curve *Curve) DoScoring(theBall *Ball, theNumBalls, theComboCount, theGapB
 		curve.Board.LevelStats.NumGaps++
 	}
 
-	text_list = append(text_list, fmt.Sprintf("+%d", num_points))
+	texts.Bytes = AppendTr(texts.Bytes, "score.points", int64(num_points))
+	texts.EndText()
 	if theComboCount > 0 {
-		text_list = append(text_list, fmt.Sprintf("COMBO x%d", theComboCount+1))
+		texts.Bytes = AppendTr(texts.Bytes, "score.combo", int64(theComboCount+1))
+		texts.EndText()
 	}
 	if theGapBonus > 0 {
-		gap_string := "GAP BONUS"
-		if theNumGaps > 3 {
-			gap_string = fmt.Sprintf("%dx GAP BONUS", theNumGaps)
-		} else if theNumGaps == 3 {
-			gap_string = "TRIPLE GAP BONUS"
-		} else if theNumGaps == 2 {
-			gap_string = "DOUBLE GAP BONUS"
-		}
-		text_list = append(text_list, gap_string)
+		texts.Bytes = AppendTrN(texts.Bytes, "score.gap_bonus", int64(theNumGaps), int64(theNumGaps))
+		texts.EndText()
 		for i := range theNumGaps {
-			curve.Board.SoundMgr.AddSound(gSounds[Sound_GapBonus], i*15, 0, float32(i+1))
+			curve.Board.SoundMgr.AddSound(Sound_GapBonus, i*15, pan, float32(i+1))
 		}
 	}
 
 	if in_a_row {
-		text_list = append(text_list, fmt.Sprintf("CHAIN BONUS x%d", curve.Board.NumClearsInARow))
-		rl.SetSoundPitch(gSounds[Sound_Chain], float32(curve.Board.NumClearsInARow)-5)
-		curve.Board.SoundMgr.AddSound(gSounds[Sound_Chain], 0, 0, float32(curve.Board.NumClearsInARow-5))
+		texts.Bytes = AppendTr(texts.Bytes, "score.chain_bonus", int64(curve.Board.NumClearsInARow))
+		texts.EndText()
+		curve.Board.SoundMgr.AddSound(Sound_Chain, 0, pan, float32(curve.Board.NumClearsInARow-5))
 	}
 
 	clr_x, clr_y := curve.Board.ClearedXSum/theNumBalls, curve.Board.ClearedYSum/theNumBalls
 	if globalGotPowerUp[PowerType_SlowDown] {
-		text_list = append(text_list, "SLOWDOWN Ball")
+		texts.Bytes = AppendTr(texts.Bytes, "powerup.slowdown")
+		texts.EndText()
 	}
 	if globalGotPowerUp[PowerType_MoveBackwards] {
-		text_list = append(text_list, "BACKWARDS Ball")
+		texts.Bytes = AppendTr(texts.Bytes, "powerup.backwards")
+		texts.EndText()
 	}
 	if globalGotPowerUp[PowerType_Accuracy] {
-		text_list = append(text_list, "ACCURACY Ball")
+		texts.Bytes = AppendTr(texts.Bytes, "powerup.accuracy")
+		texts.EndText()
 	}
-	AddTextsToMgr(text_list, FontType_Float, curve.Board.ParticleMgr, globalTextBallColors[theBall.Type], clr_x, clr_y, 0, 0)
+	AddTextsToMgr(texts, FontType_Float, curve.Board.ParticleMgr, globalTextBallColors[theBall.Type], clr_x, clr_y, 0, 0)
 }
 
 func (curve *Curve) DrawBalls(theDrawer *BallDrawer) {
@@ -778,22 +841,14 @@ func (curve *Curve) DrawBalls(theDrawer *BallDrawer) {
 		if next_ball != nil && priority > curve.WayPointMgr.GetPriority(next_ball) {
 			next_priority = curve.WayPointMgr.GetPriority(next_ball)
 		}
-		num_balls := theDrawer.NumBalls[priority]
-		theDrawer.NumBalls[priority]++
-		theDrawer.Balls[priority][num_balls] = ball
-		num_shadows := theDrawer.NumShadows[next_priority]
-		theDrawer.NumShadows[next_priority]++
-		theDrawer.Shadows[next_priority][num_shadows] = ball
+		theDrawer.Balls[priority] = append(theDrawer.Balls[priority], ball)
+		theDrawer.Shadows[next_priority] = append(theDrawer.Shadows[next_priority], ball)
 	}
 	for i := range curve.BulletList {
 		bullet := curve.BulletList[i]
 		priority := curve.WayPointMgr.GetPriority3(bullet)
-		num_balls := theDrawer.NumBalls[priority]
-		theDrawer.NumBalls[priority]++
-		theDrawer.Balls[priority][num_balls] = &bullet.Ball
-		num_shadows := theDrawer.NumShadows[priority]
-		theDrawer.NumShadows[priority]++
-		theDrawer.Shadows[priority][num_shadows] = &bullet.Ball
+		theDrawer.Balls[priority] = append(theDrawer.Balls[priority], &bullet.Ball)
+		theDrawer.Shadows[priority] = append(theDrawer.Shadows[priority], &bullet.Ball)
 	}
 }
 
@@ -820,7 +875,7 @@ func (curve *Curve) DrawPathSparkles(theStartPoint, theStagger int32, addSound b
 					path_highlight_pitch++
 				}
 			}
-			curve.Board.SoundMgr.AddSound(gSounds[Sound_LightTrail], theStagger, 0, float32(path_highlight_pitch)*0.8)
+			curve.Board.SoundMgr.AddSound(Sound_LightTrail, theStagger, 0, float32(path_highlight_pitch)*0.8)
 		}
 		path_highlight_wp += 11
 		theStagger++
@@ -828,7 +883,7 @@ func (curve *Curve) DrawPathSparkles(theStartPoint, theStagger int32, addSound b
 	}
 
 	if addSound {
-		curve.Board.SoundMgr.AddSound(gSounds[Sound_LightTrailEnd], theStagger, 0, 0)
+		curve.Board.SoundMgr.AddSound(Sound_LightTrailEnd, theStagger, 0, 0)
 	}
 	curve.SpriteMgr.AddHoleFlash(curve.CurveIndex, theStagger)
 
@@ -928,6 +983,13 @@ func (curve *Curve) GetPoint(thePoint int32, x, y, pri *int32) {
 	*pri = int32(way_point.Priority)
 }
 
+func (curve *Curve) HasReachedHole() bool {
+	if len(curve.BallList) == 0 {
+		return false
+	}
+	return curve.BallList[len(curve.BallList)-1].WayPoint >= float32(curve.WayPointMgr.GetEndPoint())
+}
+
 func (curve *Curve) HasReachedCruisingSpeed() bool {
 	return curve.AdvanceSpeed-curve.CurveDesc.Speed < 0.1
 }
@@ -941,13 +1003,15 @@ func (curve *Curve) RemoveBallsAtFront() {
 		}
 		index++
 		curve.DeleteBullet(ball.Bullet)
-		curve.BallList = slices.DeleteFunc(curve.BallList, func(b *Ball) bool { return b == ball })
+		ball.RemoveFromList(&curve.BallList)
+		curve.BallGridDirty = true
 
 		if ball.ClearCount == 0 {
 			curve.Board.UpdateBallColorMap(ball, false)
 		}
 		if ball.ClearCount != 0 || curve.StopAddingBalls {
 			curve.DeleteBall(ball)
+			ReleaseBall(ball)
 		} else {
 			curve.PendingBalls = slices.Insert(curve.PendingBalls, 0, ball)
 		}
@@ -1003,12 +1067,13 @@ func (curve *Curve) SetStopAddingBalls(stop bool) {
 	}
 }
 
-func (curve *Curve) SetupLevel(theDesc *LevelDesc, theSpriteMgr *SpriteMgr, theCurveIndex int32) {
+func (curve *Curve) SetupLevel(theDesc *LevelDesc, theSpriteMgr *SpriteMgr, theCurveIndex int32, thePathPoints []PathPoint) {
 	curve.LevelDesc = theDesc
 	curve.CurveDesc = &theDesc.CurveDescs[theCurveIndex]
 	curve.SpriteMgr = theSpriteMgr
-	curve.WayPointMgr.LoadCurve(theDesc.CurveDescs[theCurveIndex].FilePath)
+	curve.WayPointMgr.LoadCurve(thePathPoints)
 	curve.CurveIndex = theCurveIndex
+	curve.BuildWayPointGrid()
 
 	skull_rotation := float32(curve.CurveDesc.SkullRotation)
 	if skull_rotation >= 0 {
@@ -1032,6 +1097,26 @@ func (curve *Curve) SetupLevel(theDesc *LevelDesc, theSpriteMgr *SpriteMgr, theC
 	}
 }
 
+// ReloadPath swaps the path of the curve while the level runs, every ball keeps its position
+// relative to the length of the path.
+func (curve *Curve) ReloadPath(theSpriteMgr *SpriteMgr, thePathPoints []PathPoint) {
+	old_num_points := curve.WayPointMgr.GetNumPoints()
+	curve.WayPointMgr = new(WayPointMgr)
+	curve.SetupLevel(curve.LevelDesc, theSpriteMgr, curve.CurveIndex, thePathPoints)
+	new_num_points := curve.WayPointMgr.GetNumPoints()
+	if old_num_points == 0 || new_num_points == 0 {
+		return
+	}
+	scale := float32(new_num_points) / float32(old_num_points)
+	for _, list := range [][]*Ball{curve.BallList, curve.PendingBalls} {
+		for _, ball := range list {
+			curve.WayPointMgr.SetWayPoint(ball, ball.WayPoint*scale)
+			ball.SetRotation(curve.WayPointMgr.GetRotationForPoint(int(ball.WayPoint)), true)
+		}
+	}
+	curve.LastClearedBallPoint = int32(float32(curve.LastClearedBallPoint) * scale)
+}
+
 func (curve *Curve) StartClearCount(theBall *Ball) {
 	if theBall.ClearCount > 0 {
 		return
@@ -1134,8 +1219,10 @@ func (curve *Curve) UpdatePowerUps() {
 
 	for i := range PowerType_Max {
 		freq := curve.CurveDesc.PowerUpFreq[i]
-		if freq > 0 && rand.Int31n(freq) == 0 && freq < curve.Board.StateCount-curve.LastPowerUpFrame[i] {
-			curve.AddPowerUp(i)
+		if freq > 0 && globalRand.Int31n(freq) == 0 && freq < curve.Board.StateCount-curve.LastPowerUpFrame[i] {
+			if curve.AddPowerUp(i) {
+				curve.Board.LevelStats.NumPowerUpsSpawned[i]++
+			}
 			curve.LastPowerUpFrame[i] = curve.Board.StateCount
 		}
 	}
@@ -1168,8 +1255,9 @@ func (curve *Curve) UpdateSets() {
 				}
 			}
 			curve.DeleteBall(ball)
-			curve.BallList[index] = nil
-			curve.BallList = slices.Delete(curve.BallList, index, index+1)
+			ball.RemoveFromList(&curve.BallList)
+			curve.BallGridDirty = true
+			ReleaseBall(ball)
 			index++
 			len--
 		}
@@ -1217,7 +1305,7 @@ func (curve *Curve) UpdateSuckingBalls() {
 		new_way_point := (ball.WayPoint - float32(DefaultBallRadius)) - float32(DefaultBallRadius)
 		if prev_ball.WayPoint > new_way_point {
 			curve.WayPointMgr.SetWayPoint(prev_ball, new_way_point)
-			curve.Board.PlayBallClick(Sound_BallClick1)
+			curve.Board.PlayBallClick(Sound_BallClick1, prev_ball.X)
 			prev_ball.CollidesWithNext = true
 			ball.SuckCount = 0
 			if !curve.CheckSet(ball) {
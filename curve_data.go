@@ -2,8 +2,8 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"io"
-	"os"
 )
 
 type PathPoint struct {
@@ -14,34 +14,52 @@ type PathPoint struct {
 
 const INV_SUBPIXEL_MULT float32 = 0.01
 
-func LoadCurveData(filePath string) []PathPoint {
-	raw, _ := os.ReadFile(filePath)
+// LoadCurveData reads the points of a curve .dat file. The file starts with a 12 byte header and
+// an editor buffer that is skipped, then the first point is stored absolute and the others as deltas.
+func LoadCurveData(filePath string) ([]PathPoint, error) {
+	raw, err := globalVFS.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var read_err error = nil
 	reader := bytes.NewReader(raw)
 	reader.Seek(12, io.SeekStart)
-	buffer_size := ReadData[uint32](reader)
+	buffer_size := ReadData[uint32](reader, &read_err)
 	reader.Seek(int64(buffer_size), io.SeekCurrent)
 
-	size := ReadData[uint32](reader)
+	size := ReadData[uint32](reader, &read_err)
+	if read_err != nil {
+		return nil, fmt.Errorf("%s: truncated header: %w", filePath, read_err)
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("%s: the curve has no points", filePath)
+	}
+	if int64(size) > int64(reader.Len()) {
+		return nil, fmt.Errorf("%s: %d points do not fit in %d bytes", filePath, size, reader.Len())
+	}
 	point_list := make([]PathPoint, 0, size)
 	if size > 0 {
 		start_point := PathPoint{}
-		start_point.X = ReadData[float32](reader)
-		start_point.Y = ReadData[float32](reader)
-		start_point.InTunnel = ReadData[uint8](reader) != 0
-		start_point.Priority = ReadData[uint8](reader)
+		start_point.X = ReadData[float32](reader, &read_err)
+		start_point.Y = ReadData[float32](reader, &read_err)
+		start_point.InTunnel = ReadData[uint8](reader, &read_err) != 0
+		start_point.Priority = ReadData[uint8](reader, &read_err)
 		point_list = append(point_list, start_point)
 
 		ox, oy := start_point.X, start_point.Y
 		for range size - 1 {
 			point := PathPoint{}
-			dx, dy := ReadData[int8](reader), ReadData[int8](reader)
+			dx, dy := ReadData[int8](reader, &read_err), ReadData[int8](reader, &read_err)
 			point.X = float32(dx)*INV_SUBPIXEL_MULT + ox
 			point.Y = float32(dy)*INV_SUBPIXEL_MULT + oy
-			point.InTunnel = ReadData[uint8](reader) != 0
-			point.Priority = ReadData[uint8](reader)
+			point.InTunnel = ReadData[uint8](reader, &read_err) != 0
+			point.Priority = ReadData[uint8](reader, &read_err)
 			point_list = append(point_list, point)
 			ox, oy = point.X, point.Y
 		}
 	}
-	return point_list
+	if read_err != nil {
+		return nil, fmt.Errorf("%s: truncated after %d of %d points: %w", filePath, len(point_list)-1, size, read_err)
+	}
+	return point_list, nil
 }
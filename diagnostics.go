@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log/slog"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// Diagnostics collects the problems found while loading. Instead of crashing on broken assets the game
+// lists them on a screen at startup, the player can continue unless one of them is fatal.
+type Diagnostics struct {
+	Problems []string
+	Fatal    bool
+	Scroll   int32
+}
+
+// Add logs every part of theErr and lists it under theArea, nil errors are ignored.
+func (diag *Diagnostics) Add(theArea string, theErr error) {
+	for _, err := range SplitErrors(theErr) {
+		slog.Error("load failed", "area", theArea, "err", err)
+		diag.Problems = append(diag.Problems, theArea+": "+err.Error())
+	}
+}
+
+// AddFatal lists a problem the game cannot start with.
+func (diag *Diagnostics) AddFatal(theArea string, theErr error) {
+	if theErr != nil {
+		diag.Add(theArea, theErr)
+		diag.Fatal = true
+	}
+}
+
+// Show draws the problems until the player continues with enter or closes the window, it returns
+// whether to go on. The text uses the raylib font, since the problem may well be the game fonts.
+func (diag *Diagnostics) Show(theDisplay *Display) bool {
+	if len(diag.Problems) == 0 {
+		return !diag.Fatal
+	}
+	const line_height, font_size, top int32 = 16, 10, 48
+	num_visible := (GameHeight - top - 32) / line_height
+	for !rl.WindowShouldClose() {
+		theDisplay.Update()
+		if rl.IsKeyPressed(rl.KeyEnter) && !IsAltDown() && !diag.Fatal {
+			return true
+		}
+		scroll := diag.Scroll - int32(rl.GetMouseWheelMove())
+		if rl.IsKeyPressed(rl.KeyDown) || rl.IsKeyPressedRepeat(rl.KeyDown) {
+			scroll++
+		}
+		if rl.IsKeyPressed(rl.KeyUp) || rl.IsKeyPressedRepeat(rl.KeyUp) {
+			scroll--
+		}
+		diag.Scroll = max(0, min(scroll, int32(len(diag.Problems))-num_visible))
+
+		theDisplay.Begin()
+		rl.ClearBackground(rl.NewColor(24, 16, 16, 255))
+		title := "Some assets could not be loaded"
+		hint := "Enter: continue anyway   Up/Down: scroll   Esc: quit"
+		if diag.Fatal {
+			title = "The game cannot start"
+			hint = "Up/Down: scroll   Esc: quit"
+		}
+		rl.DrawText(title, 16, 16, 20, rl.Red)
+		for i := range num_visible {
+			index := diag.Scroll + i
+			if index >= int32(len(diag.Problems)) {
+				break
+			}
+			rl.DrawText(diag.Problems[index], 16, top+i*line_height, font_size, rl.RayWhite)
+		}
+		rl.DrawText(hint, 16, GameHeight-24, font_size, rl.Gray)
+		theDisplay.End()
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"image/color"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type ScaleMode string
+
+const (
+	ScaleMode_Integer ScaleMode = "integer"
+	ScaleMode_Smooth  ScaleMode = "smooth"
+)
+
+// Display renders the game into a GameWidth x GameHeight texture and scales it into the window,
+// letterboxed to keep the aspect ratio.
+type Display struct {
+	Settings      *DisplaySettings
+	Target        rl.RenderTexture2D
+	Scale         float32
+	DestRect      rl.Rectangle
+	WindowedW     int32
+	WindowedH     int32
+	ShowRecording bool
+	BorderColor   color.RGBA
+}
+
+func NewDisplay(theSettings *DisplaySettings) *Display {
+	display := &Display{Settings: theSettings, BorderColor: rl.Black}
+	display.Target = rl.LoadRenderTexture(GameWidth, GameHeight)
+	display.WindowedW, display.WindowedH = theSettings.Width, theSettings.Height
+	if theSettings.Fullscreen {
+		display.ToggleFullscreen()
+	}
+	display.Layout()
+	return display
+}
+
+// InitWindow opens the window with the size stored in the settings, falling back to the native game size.
+func InitWindow(theSettings *DisplaySettings, theTitle string) {
+	if theSettings.Width < GameWidth/2 || theSettings.Height < GameHeight/2 {
+		theSettings.Width, theSettings.Height = GameWidth, GameHeight
+	}
+	rl.SetConfigFlags(rl.FlagWindowResizable)
+	rl.InitWindow(theSettings.Width, theSettings.Height, theTitle)
+	rl.SetWindowMinSize(int(GameWidth/2), int(GameHeight/2))
+}
+
+func (display *Display) Begin() {
+	if rl.IsWindowResized() {
+		display.Layout()
+	}
+	rl.BeginTextureMode(display.Target)
+}
+
+func (display *Display) Destroy() {
+	rl.UnloadRenderTexture(display.Target)
+}
+
+func (display *Display) End() {
+	rl.EndTextureMode()
+
+	rl.BeginDrawing()
+	rl.ClearBackground(display.BorderColor)
+	// render textures are stored upside down
+	source := rl.NewRectangle(0, 0, float32(GameWidth), -float32(GameHeight))
+	rl.DrawTexturePro(display.Target.Texture, source, display.DestRect, rl.NewVector2(0, 0), 0, rl.White)
+	// drawn outside the game image so it does not end up in the recording
+	if display.ShowRecording && int(rl.GetTime()*2)%2 == 0 {
+		rl.DrawCircle(int32(display.DestRect.X)+12, int32(display.DestRect.Y)+12, 6, rl.Red)
+	}
+	rl.EndDrawing()
+}
+
+// Layout recomputes the scale and letterbox of the game image.
+func (display *Display) Layout() {
+	window_w, window_h := float32(rl.GetScreenWidth()), float32(rl.GetScreenHeight())
+	scale := min(window_w/float32(GameWidth), window_h/float32(GameHeight))
+	if display.Settings.ScaleMode == ScaleMode_Integer && scale >= 1 {
+		scale = float32(int32(scale))
+	}
+	if display.Settings.ScaleMode == ScaleMode_Smooth {
+		rl.SetTextureFilter(display.Target.Texture, rl.FilterBilinear)
+	} else {
+		rl.SetTextureFilter(display.Target.Texture, rl.FilterPoint)
+	}
+
+	display.Scale = scale
+	width, height := float32(GameWidth)*scale, float32(GameHeight)*scale
+	display.DestRect = rl.NewRectangle(float32(int32((window_w-width)/2)), float32(int32((window_h-height)/2)), width, height)
+}
+
+// GetMousePosition maps the window mouse position back into game space.
+func (display *Display) GetMousePosition() rl.Vector2 {
+	mouse := rl.GetMousePosition()
+	return rl.NewVector2((mouse.X-display.DestRect.X)/display.Scale, (mouse.Y-display.DestRect.Y)/display.Scale)
+}
+
+func (display *Display) SetScaleMode(theMode ScaleMode) {
+	display.Settings.ScaleMode = theMode
+	display.Layout()
+}
+
+// ToggleFullscreen switches between a borderless fullscreen window at the monitor resolution
+// and the last windowed size.
+func (display *Display) ToggleFullscreen() {
+	if rl.IsWindowState(rl.FlagBorderlessWindowedMode) {
+		rl.ToggleBorderlessWindowed()
+		rl.SetWindowSize(int(display.WindowedW), int(display.WindowedH))
+		display.Settings.Fullscreen = false
+	} else {
+		display.WindowedW, display.WindowedH = int32(rl.GetScreenWidth()), int32(rl.GetScreenHeight())
+		rl.ToggleBorderlessWindowed()
+		display.Settings.Fullscreen = true
+	}
+	display.Layout()
+}
+
+// StoreWindowSize remembers the windowed size in the settings, so the next start opens the same window.
+func (display *Display) StoreWindowSize() {
+	if !display.Settings.Fullscreen {
+		display.WindowedW, display.WindowedH = int32(rl.GetScreenWidth()), int32(rl.GetScreenHeight())
+	}
+	display.Settings.Width, display.Settings.Height = display.WindowedW, display.WindowedH
+}
+
+func (display *Display) Update() {
+	if rl.IsKeyPressed(rl.KeyF11) || (rl.IsKeyPressed(rl.KeyEnter) && IsAltDown()) {
+		display.ToggleFullscreen()
+	}
+}
+
+// IsAltDown tells whether an alt key is held, enter then toggles fullscreen and nothing else should take it.
+func IsAltDown() bool {
+	return rl.IsKeyDown(rl.KeyLeftAlt) || rl.IsKeyDown(rl.KeyRightAlt)
+}
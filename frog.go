@@ -20,6 +20,8 @@ type Frog struct {
 	Wink               bool
 	FireVel            float32
 	ShowNextBall       bool
+	ForcedType         int32
+	ForcedPower        PowerType
 }
 
 type FrogState int32
@@ -50,9 +52,26 @@ func NewFrog() *Frog {
 		Wink:         false,
 		FireVel:      6.0,
 		ShowNextBall: true,
+		ForcedType:   -1,
+		ForcedPower:  PowerType_Max,
 	}
 }
 
+func (frog *Frog) CalcAngleTo(theX, theY float32) float32 {
+	from_center_x := theX - float32(frog.CenterX)
+	if from_center_x == 0 {
+		if theY < float32(frog.CenterY) {
+			return math.Pi
+		}
+		return 0
+	}
+	angle := math.Atan(float64(float32(frog.CenterY)-theY) / float64(from_center_x))
+	if from_center_x < 0 {
+		angle += math.Pi
+	}
+	return float32(angle + math.Pi/2)
+}
+
 func (frog *Frog) CalcAngle() {
 	if frog.Bullet == nil {
 		return
@@ -121,11 +140,21 @@ func (frog *Frog) Draw() {
 
 	if frog.ShowNextBall {
 		if frog.NextBullet != nil && frog.State != FROGSTATE_RELOADING {
+			dots := GetSprite(Texture_BallDots)
+			dot := frog.NextBullet.Type
+			if gBallTinted {
+				dot = WhiteBallType
+			}
 			rl.DrawTexturePro(
-				gTextures[Texture_BallDots], rl.NewRectangle(float32(frog.NextBullet.Type*15), 0, 15, 15),
+				dots.Texture, dots.GetCell(dot),
 				rl.NewRectangle(float32(frog.CenterX), float32(frog.CenterY), 15, 15), rl.NewVector2(7.5, 32),
-				degree, rl.White,
+				degree, GetBallTint(frog.NextBullet.Type, rl.White),
 			)
+			if globalSettings.Accessibility.BallSymbols {
+				rad := float64(degree * rl.Deg2rad)
+				DrawBallSymbol(frog.NextBullet.Type, float32(frog.CenterX)+24.5*float32(math.Sin(rad)),
+					float32(frog.CenterY)-24.5*float32(math.Cos(rad)), 2.5, 255)
+			}
 		}
 	}
 
@@ -145,14 +174,13 @@ func (frog *Frog) Draw() {
 	} else if frog.BlinkCount > 24 {
 		return
 	}
-	draw_width, draw_height := gTextures[Texture_FrogEye].Width, gTextures[Texture_FrogEye].Height/2
+	source := GetSprite(Texture_FrogEye).GetCell(blink)
 	if frog.Wink {
-		draw_width /= 2
+		source.Width /= 2
 	}
 	rl.DrawTexturePro(
-		gTextures[Texture_FrogEye],
-		rl.NewRectangle(0, float32(blink*draw_height), float32(draw_width), float32(draw_height)),
-		rl.NewRectangle(float32(frog.CenterX), float32(frog.CenterY), float32(draw_width), float32(draw_height)),
+		gTextures[Texture_FrogEye], source,
+		rl.NewRectangle(float32(frog.CenterX), float32(frog.CenterY), source.Width, source.Height),
 		rl.NewVector2(float32(gTextures[Texture_FrogEye].Width/2), 12),
 		degree, rl.White,
 	)
@@ -179,8 +207,12 @@ func (frog *Frog) NeedsReload() bool {
 }
 
 func (frog *Frog) Reload(theType int32, delay bool, thePower PowerType) {
+	if frog.ForcedType >= 0 {
+		theType, thePower = frog.ForcedType, frog.ForcedPower
+		frog.ForcedType, frog.ForcedPower = -1, PowerType_Max
+	}
 	bullet := NewBullet()
-	bullet.CurCurvePoint = make([]int32, len(globalBoard.CurveList))
+	bullet.SetNumCurves(len(globalBoard.CurveList))
 	bullet.Type = theType
 	bullet.SetPowerType(thePower, false)
 
@@ -256,7 +288,7 @@ func (frog *Frog) SwapBullets(playSound bool) {
 		return
 	}
 	if playSound {
-		rl.PlaySound(gSounds[Sound_FrogSwap])
+		PlayGameSound(Sound_FrogSwap, 1, 0, 0)
 	}
 	bullet := frog.Bullet
 	frog.Bullet = frog.NextBullet
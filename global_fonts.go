@@ -1,22 +1,54 @@
 package main
 
 import (
+	"bufio"
 	"cmp"
+	"errors"
+	"fmt"
 	"image/color"
-	"os"
+	"maps"
+	"path"
 	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 	"github.com/tidwall/gjson"
 )
 
+const FontManifestPath string = "./fonts/fonts.json"
+
+// FallbackGlyph is drawn for characters a font does not have, when the font has it.
+const FallbackGlyph rune = '?'
+
 type FontType int32
 
 const (
 	FontType_Float FontType = iota
+	FontType_Title
+	FontType_Hud
+	FontType_Menu
 )
 
 var gFonts map[FontType]BitmapFont = make(map[FontType]BitmapFont)
+var gDefaultFonts map[FontType]BitmapFont = make(map[FontType]BitmapFont)
+
+var globalFontNames map[string]FontType = map[string]FontType{
+	"float": FontType_Float,
+	"title": FontType_Title,
+	"hud":   FontType_Hud,
+	"menu":  FontType_Menu,
+}
+
+type TextAlign int32
+
+const (
+	TextAlign_Left TextAlign = iota
+	TextAlign_Center
+	TextAlign_Right
+)
 
 type BitmapFont struct {
 	Layers []FontLayer
@@ -24,87 +56,281 @@ type BitmapFont struct {
 
 type FontLayer struct {
 	Name, ImageName       string
+	ImagePaths            []string
 	Ascent, AscentPadding int32
 	SpaceWidth, ZOrder    int32
-	Mapping               map[byte]*CharShape
-	Kerning               *map[[2]byte]int32
+	LineHeight            int32
+	Fallback              rune
+	Mapping               map[rune]*CharShape
+	Kerning               *map[[2]rune]int32
 }
 
 type CharShape struct {
 	SourceRect rl.Rectangle
 	Offset     [2]int32
 	Width      int32
+	Page       int32
+}
+
+// GetShape looks up a glyph, trying the other letter case and then the fallback glyph.
+// Returns nil when none of them exist, the character is then drawn as a space.
+func (layer *FontLayer) GetShape(theChar rune) *CharShape {
+	if shape, found := layer.Mapping[theChar]; found {
+		return shape
+	}
+	for _, other := range [2]rune{unicode.ToUpper(theChar), unicode.ToLower(theChar)} {
+		if shape, found := layer.Mapping[other]; found {
+			return shape
+		}
+	}
+	return layer.Mapping[layer.Fallback]
+}
+
+func (layer *FontLayer) GetKerning(thePrev, theChar rune) int32 {
+	if layer.Kerning == nil {
+		return 0
+	}
+	return (*layer.Kerning)[[2]rune{thePrev, theChar}]
+}
+
+// FontText is the text the fonts draw, either a string or the bytes of a reused buffer.
+type FontText interface{ ~string | ~[]byte }
+
+// nextRune decodes the character at byte i of the text and returns it with its size.
+func nextRune[T FontText](text T, i int) (rune, int) {
+	if c := text[i]; c < utf8.RuneSelf {
+		return rune(c), 1
+	}
+	var buf [utf8.UTFMax]byte
+	n := copy(buf[:], text[i:])
+	return utf8.DecodeRune(buf[:n])
 }
 
+// DrawText draws the layers in the order the loaders sorted them by ZOrder.
 func (font BitmapFont) DrawText(text string, x, y int32, theColor color.RGBA) {
-	layers := slices.Clone(font.Layers)
-	slices.SortStableFunc(layers, func(a, b FontLayer) int { return cmp.Compare(a.ZOrder, b.ZOrder) })
+	drawFontText(font, text, x, y, theColor)
+}
+
+// DrawBytes is DrawText for text formatted into a byte buffer, it does not allocate.
+func (font BitmapFont) DrawBytes(text []byte, x, y int32, theColor color.RGBA) {
+	drawFontText(font, text, x, y, theColor)
+}
+
+func drawFontText[T FontText](font BitmapFont, text T, x, y int32, theColor color.RGBA) {
+	layers := font.Layers
 	for i := range layers {
-		cx, cy := x, y
-		texture := gFontTextures[layers[i].ImageName]
-		var last_char byte = 0
-		for k := range text {
-			if text[k] != ' ' {
-				the_shape := layers[i].Mapping[text[k]]
-				offset_x, offset_y := the_shape.Offset[0], the_shape.Offset[1]
-				kern := layers[i].Kerning
-				if kern != nil {
-					if kern_val, found := (*kern)[[2]byte{last_char, text[k]}]; found {
-						offset_x += kern_val
-					}
-				}
-				rl.DrawTextureRec(texture, the_shape.SourceRect, vec2(cx+offset_x, cy-(layers[i].Ascent-the_shape.Offset[1]-offset_y)), theColor)
+		cx := x
+		var last_char rune = 0
+		for k, size := 0, 0; k < len(text); k += size {
+			var char rune
+			char, size = nextRune(text, k)
+			the_shape := layers[i].GetShape(char)
+			if char != ' ' && the_shape != nil {
+				texture := gFontTextures[layers[i].ImagePaths[the_shape.Page]]
+				offset_x := the_shape.Offset[0] + layers[i].GetKerning(last_char, char)
+				rl.DrawTextureRec(texture, the_shape.SourceRect, vec2(cx+offset_x, y-layers[i].Ascent+the_shape.Offset[1]), theColor)
 				cx += the_shape.Width
-				cy += offset_y
 			} else {
 				cx += layers[i].SpaceWidth
 			}
-			last_char = text[k]
+			last_char = char
+		}
+	}
+}
+
+// DrawTextAligned draws a single line with x as its left edge, center or right edge.
+func (font BitmapFont) DrawTextAligned(text string, x, y int32, theAlign TextAlign, theColor color.RGBA) {
+	switch theAlign {
+	case TextAlign_Center:
+		x -= font.StringWidth(text) / 2
+	case TextAlign_Right:
+		x -= font.StringWidth(text)
+	}
+	font.DrawText(text, x, y, theColor)
+}
+
+// DrawTextWrapped wraps the text to theWidth and draws the lines below each other.
+// Returns the height of the drawn text.
+func (font BitmapFont) DrawTextWrapped(text string, x, y, theWidth int32, theAlign TextAlign, theColor color.RGBA) int32 {
+	switch theAlign {
+	case TextAlign_Center:
+		x += theWidth / 2
+	case TextAlign_Right:
+		x += theWidth
+	}
+	lines := font.WrapText(text, theWidth)
+	line_height := font.LineHeight()
+	for i := range lines {
+		font.DrawTextAligned(lines[i], x, y+int32(i)*line_height, theAlign, theColor)
+	}
+	return int32(len(lines)) * line_height
+}
+
+// WrapText splits the text at newlines and between words so every line fits in theWidth.
+// A single word wider than theWidth gets a line of its own.
+func (font BitmapFont) WrapText(text string, theWidth int32) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line == "" {
+				line = word
+			} else if font.StringWidth(line+" "+word) <= theWidth {
+				line += " " + word
+			} else {
+				lines = append(lines, line)
+				line = word
+			}
 		}
+		lines = append(lines, line)
 	}
+	return lines
+}
+
+func (font BitmapFont) LineHeight() int32 {
+	var height int32 = 0
+	for i := range font.Layers {
+		height = max(height, font.Layers[i].LineHeight)
+	}
+	return height
 }
 
 func (font BitmapFont) StringWidth(text string) int32 {
+	return fontTextWidth(font, text)
+}
+
+func (font BitmapFont) BytesWidth(text []byte) int32 {
+	return fontTextWidth(font, text)
+}
+
+func fontTextWidth[T FontText](font BitmapFont, text T) int32 {
 	var max_width int32 = 0
 	for i := range font.Layers {
 		var total_width int32 = 0
-		var last_char byte = 0
-		for k := range text {
-			if text[k] != ' ' {
-				the_shape := font.Layers[i].Mapping[text[k]]
-				total_width += the_shape.Width
-				kern := font.Layers[i].Kerning
-				if kern != nil {
-					if kern_val, found := (*kern)[[2]byte{last_char, text[k]}]; found {
-						total_width += kern_val
-					}
-				}
+		var last_char rune = 0
+		for k, size := 0, 0; k < len(text); k += size {
+			var char rune
+			char, size = nextRune(text, k)
+			the_shape := font.Layers[i].GetShape(char)
+			if char != ' ' && the_shape != nil {
+				total_width += the_shape.Width + font.Layers[i].GetKerning(last_char, char)
 			} else {
 				total_width += font.Layers[i].SpaceWidth
 			}
-			last_char = text[k]
+			last_char = char
 		}
 		max_width = max(max_width, total_width)
 	}
 	return max_width
 }
 
+func (font BitmapFont) GetImagePaths() []string {
+	var paths []string
+	for i := range font.Layers {
+		for _, image_path := range font.Layers[i].ImagePaths {
+			if !slices.Contains(paths, image_path) {
+				paths = append(paths, image_path)
+			}
+		}
+	}
+	return paths
+}
+
 var gFontTextures map[string]rl.Texture2D = make(map[string]rl.Texture2D)
 
 func DestroyFontTextures() {
 	for i := range gFontTextures {
-		rl.UnloadTexture(gFontTextures[i])
+		UnloadGameTexture(gFontTextures[i])
 	}
 }
 
-func InitFonts() {
-	gFonts[FontType_Float] = loadFont("./fonts/CancunFloat14.json")
+// InitFonts loads the fonts listed in the font manifest. Fonts missing from it use the float font.
+func InitFonts() error {
+	raw, err := globalVFS.ReadFile(FontManifestPath)
+	if err != nil {
+		return err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return fmt.Errorf("%s: invalid json", FontManifestPath)
+	}
+	loaded := make(map[string]BitmapFont)
+	var errs []error
+	for name, value := range gjson.Get(json, "fonts").Map() {
+		font_type, found := globalFontNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown font %q", FontManifestPath, name))
+			continue
+		}
+		file_path := path.Join(path.Dir(FontManifestPath), value.String())
+		if _, found := loaded[file_path]; !found {
+			font, err := LoadBitmapFont(file_path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: font %q: %w", FontManifestPath, name, err))
+				continue
+			}
+			loaded[file_path] = font
+		}
+		gDefaultFonts[font_type] = loaded[file_path]
+	}
+	if _, found := gDefaultFonts[FontType_Float]; !found {
+		return errors.Join(append(errs, fmt.Errorf("%s: missing font \"float\"", FontManifestPath))...)
+	}
+	for _, font_type := range globalFontNames {
+		if _, found := gDefaultFonts[font_type]; !found {
+			gDefaultFonts[font_type] = gDefaultFonts[FontType_Float]
+		}
+	}
+	maps.Copy(gFonts, gDefaultFonts)
+	return errors.Join(errs...)
+}
+
+// ReloadFonts reads the font manifest and the default fonts again, their textures stay loaded.
+// Without a float font the old fonts are kept.
+func ReloadFonts() error {
+	old_fonts := maps.Clone(gDefaultFonts)
+	clear(gDefaultFonts)
+	err := InitFonts()
+	if _, found := gDefaultFonts[FontType_Float]; !found {
+		maps.Copy(gDefaultFonts, old_fonts)
+	}
+	RefreshFonts()
+	return err
 }
 
-func loadFont(filePath string) BitmapFont {
-	raw, _ := os.ReadFile(filePath)
+// LoadBitmapFont loads a BMFont text .fnt file or a font in the json layer format.
+func LoadBitmapFont(filePath string) (BitmapFont, error) {
+	if path.Ext(filePath) == ".fnt" {
+		return loadBMFont(filePath)
+	}
+	return loadFont(filePath)
+}
+
+func loadFontTexture(theImagePath string) error {
+	if _, found := gFontTextures[theImagePath]; found {
+		return nil
+	}
+	texture, err := LoadGameTexture(theImagePath)
+	if err != nil {
+		return err
+	}
+	gFontTextures[theImagePath] = texture
+	return nil
+}
+
+func loadFont(filePath string) (BitmapFont, error) {
+	raw, err := globalVFS.ReadFile(filePath)
+	if err != nil {
+		return BitmapFont{}, err
+	}
 	json := string(raw)
+	if !gjson.Valid(json) {
+		return BitmapFont{}, fmt.Errorf("%s: invalid json", filePath)
+	}
 	layers := gjson.Get(json, "Layers").Array()
+	if len(layers) == 0 {
+		return BitmapFont{}, fmt.Errorf("%s: no layers", filePath)
+	}
 	font := BitmapFont{Layers: make([]FontLayer, len(layers))}
 	for k := range layers {
 		obj := layers[k].Map()
@@ -114,53 +340,189 @@ func loadFont(filePath string) BitmapFont {
 			Ascent:        int32(obj["Ascent"].Int()),
 			AscentPadding: int32(obj["AscentPadding"].Int()),
 			SpaceWidth:    int32(obj["SpaceWidth"].Int()),
-			Mapping:       make(map[byte]*CharShape),
+			Fallback:      FallbackGlyph,
+			Mapping:       make(map[rune]*CharShape),
+		}
+		fail := func(format string, args ...any) (BitmapFont, error) {
+			return BitmapFont{}, fmt.Errorf("%s: layer %q: %s", filePath, layer.Name, fmt.Sprintf(format, args...))
 		}
+		TryGetAndSet(obj, "Fallback", func(r gjson.Result) {
+			if fallback := []rune(r.String()); len(fallback) != 0 {
+				layer.Fallback = fallback[0]
+			}
+		})
 
-		if _, found := gFontTextures[layer.ImageName]; !found {
-			gFontTextures[layer.ImageName] = rl.LoadTexture("./fonts/" + layer.ImageName + ".png")
+		layer.ImagePaths = []string{path.Join(path.Dir(filePath), layer.ImageName+".png")}
+		if _, err := globalVFS.Stat(layer.ImagePaths[0]); err != nil {
+			return fail("%v", err)
 		}
 
 		char_list := gjson.Get(json, obj["Chars"].String()).Array()
-		cache_chars := make([]byte, len(char_list))
+		cache_chars := make([]rune, len(char_list))
 		for i := range char_list {
-			char := char_list[i].String()[0]
-			cache_chars[i] = char
-			layer.Mapping[char] = new(CharShape)
+			char := []rune(char_list[i].String())
+			if len(char) != 1 {
+				return fail("char %d: expected one character, got %q", i, char_list[i].String())
+			}
+			cache_chars[i] = char[0]
+			layer.Mapping[char[0]] = new(CharShape)
 		}
-
 		char_widths := gjson.Get(json, obj["CharWidths"].String()).Array()
+		char_offsets := gjson.Get(json, obj["CharOffsets"].String()).Array()
+		char_srcrects := gjson.Get(json, obj["CharSrcRects"].String()).Array()
+		for name, list := range map[string][]gjson.Result{"widths": char_widths, "offsets": char_offsets, "source rects": char_srcrects} {
+			if len(list) > len(cache_chars) {
+				return fail("%d char %s for %d chars", len(list), name, len(cache_chars))
+			}
+		}
+
 		for i := range char_widths {
 			layer.Mapping[cache_chars[i]].Width = int32(char_widths[i].Int())
 		}
 
-		char_offsets := gjson.Get(json, obj["CharOffsets"].String()).Array()
 		for i := range char_offsets {
 			tmp := char_offsets[i].Array()
+			if len(tmp) != 2 {
+				return fail("char %q: expected 2 offsets, got %d", cache_chars[i], len(tmp))
+			}
 			layer.Mapping[cache_chars[i]].Offset = [2]int32{int32(tmp[0].Int()), int32(tmp[1].Int())}
 		}
 
-		char_srcrects := gjson.Get(json, obj["CharSrcRects"].String()).Array()
 		for i := range char_srcrects {
 			tmp := char_srcrects[i].Array()
+			if len(tmp) != 4 {
+				return fail("char %q: expected 4 source rect values, got %d", cache_chars[i], len(tmp))
+			}
 			layer.Mapping[cache_chars[i]].SourceRect = rl.NewRectangle(
 				float32(tmp[0].Int()), float32(tmp[1].Int()), float32(tmp[2].Int()), float32(tmp[3].Int()),
 			)
+			layer.LineHeight = max(layer.LineHeight, int32(tmp[3].Int()))
 		}
+		TryGetAndSet(obj, "LineHeight", func(r gjson.Result) { layer.LineHeight = int32(r.Int()) })
 
 		if kerning, found := obj["Kerning"]; found {
-			tmp_map := make(map[[2]byte]int32)
+			tmp_map := make(map[[2]rune]int32)
 			pairs_and_values := kerning.Array()
+			if len(pairs_and_values) != 2 {
+				return fail("kerning: expected the names of the pairs and the values")
+			}
 			pairs := gjson.Get(json, pairs_and_values[0].String()).Array()
 			values := gjson.Get(json, pairs_and_values[1].String()).Array()
+			if len(pairs) != len(values) {
+				return fail("kerning: %d pairs but %d values", len(pairs), len(values))
+			}
 			for i := range pairs {
-				pair_str, value_int := pairs[i].String(), values[i].Int()
-				tmp_map[[2]byte{pair_str[0], pair_str[1]}] = int32(value_int)
+				pair, value_int := []rune(pairs[i].String()), values[i].Int()
+				if len(pair) != 2 {
+					return fail("kerning pair %d: expected two characters, got %q", i, pairs[i].String())
+				}
+				tmp_map[[2]rune{pair[0], pair[1]}] = int32(value_int)
 			}
 			layer.Kerning = &tmp_map
 		}
 
 		font.Layers[k] = layer
 	}
-	return font
+	// the textures are only loaded once the whole file is known to be good
+	for _, image_path := range font.GetImagePaths() {
+		if err := loadFontTexture(image_path); err != nil {
+			return BitmapFont{}, err
+		}
+	}
+	slices.SortStableFunc(font.Layers, func(a, b FontLayer) int { return cmp.Compare(a.ZOrder, b.ZOrder) })
+	return font, nil
+}
+
+// parseBMFontLine splits a line like `char id=65 x=2 file="a b.png"` into its tag and attributes.
+func parseBMFontLine(theLine string) (string, map[string]string) {
+	tag, rest, _ := strings.Cut(strings.TrimSpace(theLine), " ")
+	attrs := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, _ := strings.Cut(rest, "=")
+		if strings.HasPrefix(value, "\"") {
+			value, rest, _ = strings.Cut(value[1:], "\"")
+		} else {
+			value, rest, _ = strings.Cut(value, " ")
+		}
+		attrs[key] = value
+	}
+	return tag, attrs
+}
+
+// loadBMFont reads the text variant of the AngelCode BMFont format into a single layer.
+func loadBMFont(filePath string) (BitmapFont, error) {
+	layer := FontLayer{
+		Name:     path.Base(filePath),
+		Fallback: FallbackGlyph,
+		Mapping:  make(map[rune]*CharShape),
+	}
+	kerning := make(map[[2]rune]int32)
+	file, err := globalVFS.Open(filePath)
+	if err != nil {
+		return BitmapFont{}, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		tag, attrs := parseBMFontLine(scanner.Text())
+		var errs []error
+		get := func(key string) int32 {
+			value, err := strconv.Atoi(attrs[key])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+			return int32(value)
+		}
+		switch tag {
+		case "common":
+			layer.LineHeight, layer.Ascent = get("lineHeight"), get("base")
+		case "page":
+			id := int(get("id"))
+			if id < 0 || id > 255 {
+				errs = append(errs, fmt.Errorf("page id %d out of range", id))
+				break
+			}
+			for len(layer.ImagePaths) <= id {
+				layer.ImagePaths = append(layer.ImagePaths, "")
+			}
+			layer.ImagePaths[id] = path.Join(path.Dir(filePath), attrs["file"])
+			layer.ImageName = strings.TrimSuffix(attrs["file"], path.Ext(attrs["file"]))
+			if _, err := globalVFS.Stat(layer.ImagePaths[id]); err != nil {
+				errs = append(errs, err)
+			}
+		case "char":
+			layer.Mapping[rune(get("id"))] = &CharShape{
+				SourceRect: rl.NewRectangle(float32(get("x")), float32(get("y")), float32(get("width")), float32(get("height"))),
+				Offset:     [2]int32{get("xoffset"), get("yoffset")},
+				Width:      get("xadvance"),
+				Page:       get("page"),
+			}
+		case "kerning":
+			kerning[[2]rune{rune(get("first")), rune(get("second"))}] = get("amount")
+		}
+		if len(errs) != 0 {
+			return BitmapFont{}, fmt.Errorf("%s:%d: %s: %w", filePath, line, tag, errors.Join(errs...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return BitmapFont{}, fmt.Errorf("%s: %w", filePath, err)
+	}
+	for char, shape := range layer.Mapping {
+		if shape.Page < 0 || int(shape.Page) >= len(layer.ImagePaths) || layer.ImagePaths[shape.Page] == "" {
+			return BitmapFont{}, fmt.Errorf("%s: char %d: missing page %d", filePath, char, shape.Page)
+		}
+	}
+	for _, image_path := range layer.ImagePaths {
+		if err := loadFontTexture(image_path); err != nil {
+			return BitmapFont{}, err
+		}
+	}
+	if space, found := layer.Mapping[' ']; found {
+		layer.SpaceWidth = space.Width
+	}
+	if len(kerning) > 0 {
+		layer.Kerning = &kerning
+	}
+	return BitmapFont{Layers: []FontLayer{layer}}, nil
 }
@@ -1,6 +1,16 @@
 package main
 
-import rl "github.com/gen2brain/raylib-go/raylib"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/tidwall/gjson"
+)
+
+const SoundManifestPath string = "./sounds/sounds.json"
 
 var gSounds map[SoundKey]rl.Sound = make(map[SoundKey]rl.Sound)
 
@@ -24,32 +34,116 @@ const (
 	Sound_LightTrailEnd
 )
 
-func InitGlobalSounds() {
-	gSounds[Sound_FrogFire] = rl.LoadSound("sounds/ballfire.ogg")
-	gSounds[Sound_FrogSwap] = rl.LoadSound("sounds/ballswap.ogg")
-	gSounds[Sound_BallClick1] = rl.LoadSound("sounds/ballclick1.ogg")
-	rl.SetSoundVolume(gSounds[Sound_BallClick1], 0.8)
-	gSounds[Sound_BallClick2] = rl.LoadSound("sounds/ballclick2.ogg")
-	gSounds[Sound_ExtraLife] = rl.LoadSound("sounds/extralife.ogg")
-	gSounds[Sound_GapBonus] = rl.LoadSound("sounds/gapbonus.ogg")
-	gSounds[Sound_Chain] = rl.LoadSound("sounds/chain.ogg")
-	gSounds[Sound_Combo] = rl.LoadSound("sounds/combo.wav")
-	gSounds[Sound_BallDestroyed1] = rl.LoadSound("sounds/ballsdestroyed1.ogg")
-	gSounds[Sound_BallDestroyed2] = rl.LoadSound("sounds/ballsdestroyed2.ogg")
-	gSounds[Sound_BallDestroyed3] = rl.LoadSound("sounds/ballsdestroyed3.ogg")
-	gSounds[Sound_BallDestroyed4] = rl.LoadSound("sounds/ballsdestroyed4.ogg")
-	gSounds[Sound_BallDestroyed5] = rl.LoadSound("sounds/ballsdestroyed5.ogg")
-	rl.SetSoundVolume(gSounds[Sound_BallDestroyed1], 0.8)
-	rl.SetSoundVolume(gSounds[Sound_BallDestroyed2], 0.8)
-	rl.SetSoundVolume(gSounds[Sound_BallDestroyed3], 0.85)
-	rl.SetSoundVolume(gSounds[Sound_BallDestroyed4], 0.9)
-	rl.SetSoundVolume(gSounds[Sound_BallDestroyed5], 0.95)
-	gSounds[Sound_LightTrail] = rl.LoadSound("sounds/lighttrail.ogg")
-	rl.SetSoundVolume(gSounds[Sound_LightTrail], 0.7)
-	gSounds[Sound_LightTrailEnd] = rl.LoadSound("sounds/chant3.ogg")
+// globalSoundNames maps the names used by the sound manifest to the sound keys.
+var globalSoundNames map[string]SoundKey = map[string]SoundKey{
+	"frog_fire":        Sound_FrogFire,
+	"frog_swap":        Sound_FrogSwap,
+	"ball_click1":      Sound_BallClick1,
+	"ball_click2":      Sound_BallClick2,
+	"extra_life":       Sound_ExtraLife,
+	"gap_bonus":        Sound_GapBonus,
+	"chain":            Sound_Chain,
+	"combo":            Sound_Combo,
+	"balls_destroyed1": Sound_BallDestroyed1,
+	"balls_destroyed2": Sound_BallDestroyed2,
+	"balls_destroyed3": Sound_BallDestroyed3,
+	"balls_destroyed4": Sound_BallDestroyed4,
+	"balls_destroyed5": Sound_BallDestroyed5,
+	"light_trail":      Sound_LightTrail,
+	"light_trail_end":  Sound_LightTrailEnd,
+}
+
+var globalLoopNames map[string]LoopType = map[string]LoopType{
+	"roll_in":  LoopType_RollIn,
+	"roll_out": LoopType_RollOut,
+	"danger":   LoopType_Danger,
+}
+
+type LoopFile struct {
+	FilePath string
+	Volume   float32
+}
+
+// gLoopFiles holds the files of the looping sounds, every SoundMgr loads its own copy.
+// A loop without a file falls back to its built-in sound, if it has one.
+var gLoopFiles map[LoopType]LoopFile = make(map[LoopType]LoopFile)
+
+// InitGlobalSounds loads every sound listed in the sound manifest. All problems are collected,
+// so a broken sound pack reports every missing or unknown sound at once.
+func InitGlobalSounds() error {
+	raw, err := globalVFS.ReadFile(SoundManifestPath)
+	if err != nil {
+		return err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return fmt.Errorf("%s: invalid json", SoundManifestPath)
+	}
+
+	var errs []error
+	sounds := gjson.Get(json, "sounds").Map()
+	for name, value := range sounds {
+		key, found := globalSoundNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown sound %q", SoundManifestPath, name))
+			continue
+		}
+		bus := AudioBus_Sfx
+		if value.Get("bus").Exists() {
+			if bus, err = ParseAudioBus(value.Get("bus").String()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: sound %q: %w", SoundManifestPath, name, err))
+				continue
+			}
+		}
+		volume := float32(1)
+		TryGetAndSet(value.Map(), "volume", func(r gjson.Result) { volume = float32(r.Float()) })
+		if err := LoadGameSound(key, value.Get("file").String(), volume, bus); err != nil {
+			errs = append(errs, fmt.Errorf("%s: sound %q: %w", SoundManifestPath, name, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(globalSoundNames)) {
+		if _, found := sounds[name]; !found {
+			errs = append(errs, fmt.Errorf("%s: sound %q is missing", SoundManifestPath, name))
+		}
+	}
+
+	for name, value := range gjson.Get(json, "loops").Map() {
+		key, found := globalLoopNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown loop %q", SoundManifestPath, name))
+			continue
+		}
+		loop := LoopFile{value.Get("file").String(), 1}
+		TryGetAndSet(value.Map(), "volume", func(r gjson.Result) { loop.Volume = float32(r.Float()) })
+		if _, err := globalVFS.Stat(loop.FilePath); err != nil {
+			errs = append(errs, fmt.Errorf("%s: loop %q: %w", SoundManifestPath, name, err))
+			continue
+		}
+		gLoopFiles[key] = loop
+	}
+	return errors.Join(errs...)
+}
+
+func LoadGameSound(theKey SoundKey, filePath string, theVolume float32, theBus AudioBus) error {
+	sound, err := LoadAssetSound(filePath)
+	if err != nil {
+		return err
+	}
+	gSounds[theKey] = sound
+	gSoundInfo[theKey] = SoundInfo{theVolume, theBus}
+	return nil
+}
+
+// ReloadGlobalSounds loads the sound manifest and every sound listed in it again.
+func ReloadGlobalSounds() error {
+	DestroyGlobalSounds()
+	clear(gSounds)
+	clear(gLoopFiles)
+	return InitGlobalSounds()
 }
 
 func DestroyGlobalSounds() {
+	DestroySoundAliases()
 	for i := range gSounds {
 		rl.UnloadSound(gSounds[i])
 	}
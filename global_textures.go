@@ -1,8 +1,30 @@
 package main
 
-import rl "github.com/gen2brain/raylib-go/raylib"
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/tidwall/gjson"
+)
+
+const TextureManifestPath string = "./images/textures.json"
 
 var gTextures map[TextureKey]rl.Texture2D = make(map[TextureKey]rl.Texture2D)
+var gTextureLayouts map[TextureKey]SpriteLayout = make(map[TextureKey]SpriteLayout)
+
+// The default art, gTextures holds it overlaid by the textures of the current theme.
+var gDefaultTextures map[TextureKey]rl.Texture2D = make(map[TextureKey]rl.Texture2D)
+var gDefaultTextureLayouts map[TextureKey]SpriteLayout = make(map[TextureKey]SpriteLayout)
+var gDefaultTexturePaths map[TextureKey]string = make(map[TextureKey]string)
+
+// SpriteLayout is the grid of cells a texture is cut into, most textures are a single cell.
+type SpriteLayout struct {
+	Rows, Cols int32
+}
 
 type TextureKey int32
 
@@ -66,75 +88,198 @@ const (
 	Texture_Hole
 	Texture_HoleCover
 	Texture_Life
+	Texture_Max
 )
 
-func InitGlobalTextures() {
-	gTextures[Texture_BlueBall] = rl.LoadTexture("images/baBallBlue.png")
-	gTextures[Texture_YellowBall] = rl.LoadTexture("images/baBallYellow.png")
-	gTextures[Texture_RedBall] = rl.LoadTexture("images/baBallRed.png")
-	gTextures[Texture_GreenBall] = rl.LoadTexture("images/baBallGreen.png")
-	gTextures[Texture_PurpleBall] = rl.LoadTexture("images/baBallPurple.png")
-	gTextures[Texture_WhiteBall] = rl.LoadTexture("images/baBallWhite.png")
-
-	gTextures[Texture_BallDots] = rl.LoadTexture("images/baDotz.png")
-	gTextures[Texture_BallExplosion] = rl.LoadTexture("images/grayplosion.png")
-	gTextures[Texture_BallShadow] = rl.LoadTexture("images/ballshadow.png")
-	gTextures[Texture_FrogBase] = rl.LoadTexture("images/SMALLFROGonPAD.png")
-	gTextures[Texture_FrogImageMask] = rl.LoadTexture("images/mask.png")
-	gTextures[Texture_FrogEye] = rl.LoadTexture("images/EYEBLINK.png")
-	gTextures[Texture_FrogTongue] = rl.LoadTexture("images/Tongue.png")
-	gTextures[Texture_Sparkle] = rl.LoadTexture("images/sparkle.png")
-	gTextures[Texture_Explosion] = rl.LoadTexture("images/Explosion.png")
-
-	gTextures[Texture_AccuracyLight] = rl.LoadTexture("images/baAccuracyLight.png")
-	gTextures[Texture_BackwardsLight] = rl.LoadTexture("images/baBackwardsLight.png")
-	gTextures[Texture_SlowLight] = rl.LoadTexture("images/baSlowLight.png")
-
-	gTextures[Texture_BlueLight] = rl.LoadTexture("images/baLightBlue.png")
-	gTextures[Texture_YellowLight] = rl.LoadTexture("images/baLightYellow.png")
-	gTextures[Texture_RedLight] = rl.LoadTexture("images/baLightRed.png")
-	gTextures[Texture_GreenLight] = rl.LoadTexture("images/baLightGreen.png")
-	gTextures[Texture_PurpleLight] = rl.LoadTexture("images/baLightPurple.png")
-	gTextures[Texture_WhiteLight] = rl.LoadTexture("images/baLightWhite.png")
-
-	gTextures[Texture_BlueAccuracy] = rl.LoadTexture("images/baAccuracyBlue.png")
-	gTextures[Texture_YellowAccuracy] = rl.LoadTexture("images/baAccuracyYellow.png")
-	gTextures[Texture_RedAccuracy] = rl.LoadTexture("images/baAccuracyRed.png")
-	gTextures[Texture_GreenAccuracy] = rl.LoadTexture("images/baAccuracyGreen.png")
-	gTextures[Texture_PurpleAccuracy] = rl.LoadTexture("images/baAccuracyPurple.png")
-	gTextures[Texture_WhiteAccuracy] = rl.LoadTexture("images/baAccuracyWhite.png")
-
-	gTextures[Texture_BlueBackwards] = rl.LoadTexture("images/baBackwardsBlue.png")
-	gTextures[Texture_YellowBackwards] = rl.LoadTexture("images/baBackwardsYellow.png")
-	gTextures[Texture_RedBackwards] = rl.LoadTexture("images/baBackwardsRed.png")
-	gTextures[Texture_GreenBackwards] = rl.LoadTexture("images/baBackwardsGreen.png")
-	gTextures[Texture_PurpleBackwards] = rl.LoadTexture("images/baBackwardsPurple.png")
-	gTextures[Texture_WhiteBackwards] = rl.LoadTexture("images/baBackwardsWhite.png")
-
-	gTextures[Texture_BlueBomb] = rl.LoadTexture("images/baBombBlue.png")
-	gTextures[Texture_YellowBomb] = rl.LoadTexture("images/baBombYellow.png")
-	gTextures[Texture_RedBomb] = rl.LoadTexture("images/baBombRed.png")
-	gTextures[Texture_GreenBomb] = rl.LoadTexture("images/baBombGreen.png")
-	gTextures[Texture_PurpleBomb] = rl.LoadTexture("images/baBombPurple.png")
-	gTextures[Texture_WhiteBomb] = rl.LoadTexture("images/baBombWhite.png")
-
-	gTextures[Texture_BlueSlow] = rl.LoadTexture("images/baSlowBlue.png")
-	gTextures[Texture_YellowSlow] = rl.LoadTexture("images/baSlowYellow.png")
-	gTextures[Texture_RedSlow] = rl.LoadTexture("images/baSlowRed.png")
-	gTextures[Texture_GreenSlow] = rl.LoadTexture("images/baSlowGreen.png")
-	gTextures[Texture_PurpleSlow] = rl.LoadTexture("images/baSlowPurple.png")
-	gTextures[Texture_WhiteSlow] = rl.LoadTexture("images/baSlowWhite.png")
-
-	gTextures[Texture_Hole] = rl.LoadTexture("images/Hole.png")
-	gTextures[Texture_HoleCover] = rl.LoadTexture("images/pitcover.png")
-	gTextures[Texture_Life] = rl.LoadTexture("images/Life.png")
-	for i := range gTextures {
-		rl.SetTextureFilter(gTextures[i], rl.FilterTrilinear)
+// ColorTexture is a set of textures with one texture per ball color.
+type ColorTexture int32
+
+const (
+	ColorTexture_Ball ColorTexture = iota
+	ColorTexture_Light
+	ColorTexture_Accuracy
+	ColorTexture_Backwards
+	ColorTexture_Bomb
+	ColorTexture_Slow
+	ColorTexture_Max
+)
+
+var globalColorTextures [ColorTexture_Max][6]TextureKey = [ColorTexture_Max][6]TextureKey{
+	{Texture_BlueBall, Texture_YellowBall, Texture_RedBall, Texture_GreenBall, Texture_PurpleBall, Texture_WhiteBall},
+	{Texture_BlueLight, Texture_YellowLight, Texture_RedLight, Texture_GreenLight, Texture_PurpleLight, Texture_WhiteLight},
+	{Texture_BlueAccuracy, Texture_YellowAccuracy, Texture_RedAccuracy, Texture_GreenAccuracy, Texture_PurpleAccuracy, Texture_WhiteAccuracy},
+	{Texture_BlueBackwards, Texture_YellowBackwards, Texture_RedBackwards, Texture_GreenBackwards, Texture_PurpleBackwards, Texture_WhiteBackwards},
+	{Texture_BlueBomb, Texture_YellowBomb, Texture_RedBomb, Texture_GreenBomb, Texture_PurpleBomb, Texture_WhiteBomb},
+	{Texture_BlueSlow, Texture_YellowSlow, Texture_RedSlow, Texture_GreenSlow, Texture_PurpleSlow, Texture_WhiteSlow},
+}
+
+var globalColorTextureNames [ColorTexture_Max]string = [ColorTexture_Max]string{"ball", "light", "accuracy", "backwards", "bomb", "slow"}
+
+// globalTextureNames maps the names used by the texture manifest to the texture keys.
+var globalTextureNames map[string]TextureKey = func() map[string]TextureKey {
+	names := map[string]TextureKey{
+		"ball_dots":       Texture_BallDots,
+		"ball_explosion":  Texture_BallExplosion,
+		"ball_shadow":     Texture_BallShadow,
+		"frog_base":       Texture_FrogBase,
+		"frog_mask":       Texture_FrogImageMask,
+		"frog_eye":        Texture_FrogEye,
+		"frog_tongue":     Texture_FrogTongue,
+		"sparkle":         Texture_Sparkle,
+		"explosion":       Texture_Explosion,
+		"accuracy_light":  Texture_AccuracyLight,
+		"backwards_light": Texture_BackwardsLight,
+		"slow_light":      Texture_SlowLight,
+		"hole":            Texture_Hole,
+		"hole_cover":      Texture_HoleCover,
+		"life":            Texture_Life,
+	}
+	for set := range ColorTexture_Max {
+		for i, key := range globalColorTextures[set] {
+			names[globalColorTextureNames[set]+"_"+globalBallColorNames[i]] = key
+		}
+	}
+	return names
+}()
+
+// InitGlobalTextures loads every texture listed in the texture manifest. All problems are collected,
+// so a broken skin reports every missing or unknown texture at once.
+func InitGlobalTextures() error {
+	raw, err := globalVFS.ReadFile(TextureManifestPath)
+	if err != nil {
+		return err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return fmt.Errorf("%s: invalid json", TextureManifestPath)
+	}
+
+	textures := gjson.Get(json, "textures")
+	errs := []error{LoadTextureManifest(textures, TextureManifestPath, ".", gDefaultTextures, gDefaultTextureLayouts, gDefaultTexturePaths)}
+	for _, name := range slices.Sorted(maps.Keys(globalTextureNames)) {
+		if !textures.Get(name).Exists() {
+			errs = append(errs, fmt.Errorf("%s: texture %q is missing", TextureManifestPath, name))
+		}
+	}
+	maps.Copy(gTextures, gDefaultTextures)
+	maps.Copy(gTextureLayouts, gDefaultTextureLayouts)
+	BuildAtlases()
+	return errors.Join(errs...)
+}
+
+// LoadTextureManifest loads the textures of a manifest section into theTextures, file names are relative to theDir.
+// thePaths keeps the file of every texture, for reloading it.
+func LoadTextureManifest(theManifest gjson.Result, theSource, theDir string, theTextures map[TextureKey]rl.Texture2D, theLayouts map[TextureKey]SpriteLayout, thePaths map[TextureKey]string) error {
+	var errs []error
+	for name, value := range theManifest.Map() {
+		key, found := globalTextureNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown texture %q", theSource, name))
+			continue
+		}
+		file_path := path.Join(theDir, value.Get("file").String())
+		texture, err := LoadGameTexture(file_path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: texture %q: %w", theSource, name, err))
+			continue
+		}
+		theTextures[key] = texture
+		thePaths[key] = file_path
+		theLayouts[key] = SpriteLayout{max(int32(value.Get("rows").Int()), 1), max(int32(value.Get("cols").Int()), 1)}
+		SetGameTextureFilter(theTextures[key])
 	}
+	return errors.Join(errs...)
 }
 
 func DestroyGlobalTextures() {
-	for i := range gTextures {
-		rl.UnloadTexture(gTextures[i])
+	UnloadTheme()
+	UnloadAtlases()
+	for i := range gDefaultTextures {
+		UnloadGameTexture(gDefaultTextures[i])
+	}
+	clear(gDefaultTextures)
+	clear(gDefaultTexturePaths)
+	clear(gTextures)
+}
+
+// ReloadGlobalTextures loads the texture manifest and every texture again, then the current theme on top.
+func ReloadGlobalTextures() error {
+	theme := GetThemeName()
+	DestroyGlobalTextures()
+	return errors.Join(InitGlobalTextures(), ApplyTheme(theme))
+}
+
+// ReloadTextureFile swaps every default, theme and font texture loaded from theFilePath for a fresh copy.
+// Font textures keep their key and the atlases are rebuilt, so nothing holds on to the old textures.
+func ReloadTextureFile(theFilePath string) (bool, error) {
+	reloaded := false
+	reload := func(theOld *rl.Texture2D) error {
+		texture, err := LoadGameTexture(theFilePath)
+		if err != nil {
+			return err
+		}
+		SetGameTextureFilter(texture)
+		UnloadGameTexture(*theOld)
+		*theOld, reloaded = texture, true
+		return nil
+	}
+	swap := func(theTextures map[TextureKey]rl.Texture2D, thePaths map[TextureKey]string) error {
+		for key, file_path := range thePaths {
+			if !SameAssetPath(file_path, theFilePath) {
+				continue
+			}
+			texture := theTextures[key]
+			in_use := gTextures[key] == texture
+			if err := reload(&texture); err != nil {
+				return err
+			}
+			theTextures[key] = texture
+			if in_use {
+				gTextures[key] = texture
+			}
+		}
+		return nil
+	}
+
+	var errs []error
+	if globalTheme != nil {
+		errs = append(errs, swap(globalTheme.Textures, globalTheme.TexturePaths))
+	}
+	errs = append(errs, swap(gDefaultTextures, gDefaultTexturePaths))
+	for image_path, texture := range gFontTextures {
+		if SameAssetPath(image_path, theFilePath) {
+			errs = append(errs, reload(&texture))
+			gFontTextures[image_path] = texture
+		}
+	}
+	if reloaded {
+		BuildAtlases()
+	}
+	return reloaded, errors.Join(errs...)
+}
+
+// LoadGameTexture loads a texture from the VFS. In headless mode only the texture dimensions are kept,
+// nothing is uploaded to the GPU.
+func LoadGameTexture(filePath string) (rl.Texture2D, error) {
+	image, err := LoadAssetImage(filePath)
+	if err != nil {
+		return rl.Texture2D{}, err
+	}
+	defer rl.UnloadImage(image)
+	if globalHeadless {
+		return rl.Texture2D{Width: image.Width, Height: image.Height}, nil
+	}
+	return rl.LoadTextureFromImage(image), nil
+}
+
+func SetGameTextureFilter(theTexture rl.Texture2D) {
+	if theTexture.ID != 0 {
+		rl.SetTextureFilter(theTexture, rl.FilterTrilinear)
+	}
+}
+
+func UnloadGameTexture(theTexture rl.Texture2D) {
+	if theTexture.ID != 0 {
+		rl.UnloadTexture(theTexture)
 	}
 }
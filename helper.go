@@ -5,6 +5,8 @@ import (
 	"encoding/binary"
 	"maps"
 	"math/rand"
+	"slices"
+	"time"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -17,16 +19,16 @@ func vec2(x, y int32) rl.Vector2 {
 	return rl.NewVector2(float32(x), float32(y))
 }
 
+var globalRand *rand.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+func SeedRandom(theSeed int64) {
+	globalRand = rand.New(rand.NewSource(theSeed))
+}
+
 func randomly_get_map_key(theMap map[int32]int32) int32 {
-	rd := int32(rand.Intn(len(theMap)))
-	var index, value int32
-	for value = range maps.Keys(theMap) {
-		if index == rd {
-			break
-		}
-		index++
-	}
-	return value
+	// map iteration order is random, sort the keys so seeded games are reproducible
+	keys := slices.Sorted(maps.Keys(theMap))
+	return keys[globalRand.Intn(len(keys))]
 }
 
 func widen_for_index[T int32 | float32](array *[]T, index int) {
@@ -37,8 +39,11 @@ func widen_for_index[T int32 | float32](array *[]T, index int) {
 	}
 }
 
-func ReadData[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 | float32 | float64](reader *bytes.Reader) T {
+// ReadData reads a little endian value. After the first failed read theErr keeps that error and every read returns 0.
+func ReadData[T int8 | int16 | int32 | int64 | uint8 | uint16 | uint32 | uint64 | float32 | float64](reader *bytes.Reader, theErr *error) T {
 	var tmp T
-	binary.Read(reader, binary.LittleEndian, &tmp)
+	if *theErr == nil {
+		*theErr = binary.Read(reader, binary.LittleEndian, &tmp)
+	}
 	return tmp
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+const HotReloadInterval time.Duration = 500 * time.Millisecond
+
+// globalWatchedDirs are the asset directories the hot reloader polls.
+var globalWatchedDirs []string = []string{"levels", "fonts", "images", "sounds", "themes"}
+
+type FileStamp struct {
+	Path    string
+	Layer   int
+	ModTime time.Time
+	Size    int64
+}
+
+// HotReloader polls the asset directories of every VFS layer and reloads what changed while the game runs.
+// Files are compared by modification time and size, so it works without file system notifications.
+type HotReloader struct {
+	Board    *Board
+	Parser   *LevelParser
+	Stamps   map[string]FileStamp
+	LastPoll time.Time
+}
+
+func NewHotReloader(theBoard *Board, theParser *LevelParser) *HotReloader {
+	return &HotReloader{Board: theBoard, Parser: theParser, Stamps: scanWatchedFiles(), LastPoll: time.Now()}
+}
+
+// scanWatchedFiles stamps the files of the watched directories, keyed by their lower case path.
+// A file in an upper layer hides the one below, like it does for loading.
+func scanWatchedFiles() map[string]FileStamp {
+	stamps := make(map[string]FileStamp)
+	for layer_index, layer := range globalVFS.Layers {
+		for _, dir := range globalWatchedDirs {
+			fs.WalkDir(layer, dir, func(thePath string, theEntry fs.DirEntry, theErr error) error {
+				if theErr != nil || theEntry.IsDir() {
+					return nil
+				}
+				key := strings.ToLower(thePath)
+				if _, found := stamps[key]; found {
+					return nil
+				}
+				if info, err := theEntry.Info(); err == nil {
+					stamps[key] = FileStamp{thePath, layer_index, info.ModTime(), info.Size()}
+				}
+				return nil
+			})
+		}
+	}
+	return stamps
+}
+
+// Poll looks for changed files every HotReloadInterval and reloads them.
+func (reloader *HotReloader) Poll() {
+	if time.Since(reloader.LastPoll) < HotReloadInterval {
+		return
+	}
+	reloader.LastPoll = time.Now()
+	stamps := scanWatchedFiles()
+	var changed []string
+	for key, stamp := range stamps {
+		if old, found := reloader.Stamps[key]; !found || old != stamp {
+			changed = append(changed, stamp.Path)
+		}
+	}
+	for key, stamp := range reloader.Stamps {
+		if _, found := stamps[key]; !found {
+			changed = append(changed, stamp.Path)
+		}
+	}
+	reloader.Stamps = stamps
+	if len(changed) == 0 {
+		return
+	}
+	slices.Sort(changed)
+	slog.Info("reloading changed files", "files", changed)
+	if err := reloader.Reload(changed); err != nil {
+		LogErrors(slog.LevelWarn, "reload incomplete", err)
+	}
+}
+
+// Reload applies changed files: level files are evicted from the asset cache and the running level is
+// reloaded, the level file is parsed again and reapplied, textures are swapped, fonts and sounds reloaded.
+// Music is picked up the next time a track starts.
+func (reloader *HotReloader) Reload(theChanged []string) error {
+	var errs []error
+	reload_levels, reload_level_art, reload_textures, reload_fonts, reload_sounds := false, false, false, false, false
+	reload_theme := false
+	for _, file_path := range theChanged {
+		key := strings.ToLower(file_path)
+		dir, _, _ := strings.Cut(key, "/")
+		switch {
+		case SameAssetPath(key, LevelsPath):
+			reload_levels = true
+		case dir == "levels":
+			globalAssets.Evict(file_path)
+			reload_level_art = true
+		case SameAssetPath(key, TextureManifestPath):
+			reload_textures = true
+		case dir == "themes" && path.Base(key) == "theme.json":
+			reload_theme = reload_theme || SameAssetPath(key, path.Join(ThemesDir, GetThemeName(), "theme.json"))
+		case dir == "fonts" && (path.Ext(key) == ".json" || path.Ext(key) == ".fnt"):
+			reload_fonts = true
+		case dir == "sounds" && !strings.HasPrefix(key, "sounds/music"):
+			reload_sounds = true
+		case path.Ext(key) == ".png" || path.Ext(key) == ".jpg" || path.Ext(key) == ".gif":
+			if _, err := ReloadTextureFile(file_path); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if reload_textures {
+		errs = append(errs, ReloadGlobalTextures())
+	} else if reload_theme {
+		theme := GetThemeName()
+		UnloadTheme()
+		errs = append(errs, ApplyTheme(theme))
+	}
+	if reload_fonts {
+		errs = append(errs, ReloadFonts())
+	}
+	if reload_sounds {
+		errs = append(errs, ReloadGlobalSounds())
+	}
+
+	board := reloader.Board
+	if reload_levels {
+		parser := NewLevelParser()
+		err := parser.ParseLevels(LevelsPath)
+		errs = append(errs, err)
+		if len(parser.GraphicsMap) == 0 {
+			return errors.Join(errs...)
+		}
+		*reloader.Parser = parser
+		if board.LevelDesc != nil {
+			if desc, found := parser.MakeLevel(board.LevelDesc.Name, board.LevelDesc.SettingsId); found {
+				errs = append(errs, board.ApplyLevelDesc(desc))
+				reload_level_art = false
+			}
+		}
+	}
+	if reload_level_art && board.LevelDesc != nil {
+		errs = append(errs, board.ReloadLevel())
+	}
+	return errors.Join(errs...)
+}
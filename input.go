@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type InputAction string
+
+const (
+	InputAction_RotateLeft  InputAction = "rotate_left"
+	InputAction_RotateRight InputAction = "rotate_right"
+	InputAction_Fire        InputAction = "fire"
+	InputAction_Swap        InputAction = "swap"
+	InputAction_Mute        InputAction = "mute"
+	InputAction_Screenshot  InputAction = "screenshot"
+	InputAction_Record      InputAction = "record"
+)
+
+type InputSource int32
+
+const (
+	InputSource_Key InputSource = iota
+	InputSource_Mouse
+	InputSource_GamepadButton
+	InputSource_GamepadAxis
+)
+
+type InputBinding struct {
+	Source InputSource
+	Code   int32
+}
+
+type InputMgr struct {
+	Settings    *InputSettings
+	Display     *Display
+	Bindings    map[InputAction][]InputBinding
+	Gamepad     int32
+	RotateSpeed float32
+	UseMouse    bool
+	TriggerDown [rl.GamepadAxisRightTrigger + 1]bool
+	TriggerHit  [rl.GamepadAxisRightTrigger + 1]bool
+}
+
+var globalKeyNames map[string]int32 = map[string]int32{
+	"space": rl.KeySpace, "enter": rl.KeyEnter, "tab": rl.KeyTab, "backspace": rl.KeyBackspace,
+	"left": rl.KeyLeft, "right": rl.KeyRight, "up": rl.KeyUp, "down": rl.KeyDown,
+	"lshift": rl.KeyLeftShift, "rshift": rl.KeyRightShift, "lctrl": rl.KeyLeftControl, "rctrl": rl.KeyRightControl,
+	"lalt": rl.KeyLeftAlt, "ralt": rl.KeyRightAlt,
+	"kp0": rl.KeyKp0, "kp1": rl.KeyKp1, "kp2": rl.KeyKp2, "kp3": rl.KeyKp3, "kp4": rl.KeyKp4,
+	"kp5": rl.KeyKp5, "kp6": rl.KeyKp6, "kp7": rl.KeyKp7, "kp8": rl.KeyKp8, "kp9": rl.KeyKp9,
+	"f1": rl.KeyF1, "f2": rl.KeyF2, "f3": rl.KeyF3, "f4": rl.KeyF4, "f5": rl.KeyF5, "f6": rl.KeyF6,
+	"f7": rl.KeyF7, "f8": rl.KeyF8, "f9": rl.KeyF9, "f10": rl.KeyF10, "f11": rl.KeyF11, "f12": rl.KeyF12,
+}
+
+var globalInputNames map[string]InputBinding = map[string]InputBinding{
+	"mouse_left":   {InputSource_Mouse, int32(rl.MouseButtonLeft)},
+	"mouse_right":  {InputSource_Mouse, int32(rl.MouseButtonRight)},
+	"mouse_middle": {InputSource_Mouse, int32(rl.MouseButtonMiddle)},
+	"pad_a":        {InputSource_GamepadButton, rl.GamepadButtonRightFaceDown},
+	"pad_b":        {InputSource_GamepadButton, rl.GamepadButtonRightFaceRight},
+	"pad_x":        {InputSource_GamepadButton, rl.GamepadButtonRightFaceLeft},
+	"pad_y":        {InputSource_GamepadButton, rl.GamepadButtonRightFaceUp},
+	"pad_lb":       {InputSource_GamepadButton, rl.GamepadButtonLeftTrigger1},
+	"pad_rb":       {InputSource_GamepadButton, rl.GamepadButtonRightTrigger1},
+	"pad_left":     {InputSource_GamepadButton, rl.GamepadButtonLeftFaceLeft},
+	"pad_right":    {InputSource_GamepadButton, rl.GamepadButtonLeftFaceRight},
+	"pad_up":       {InputSource_GamepadButton, rl.GamepadButtonLeftFaceUp},
+	"pad_down":     {InputSource_GamepadButton, rl.GamepadButtonLeftFaceDown},
+	"pad_lt":       {InputSource_GamepadAxis, rl.GamepadAxisLeftTrigger},
+	"pad_rt":       {InputSource_GamepadAxis, rl.GamepadAxisRightTrigger},
+}
+
+func NewInputMgr(theSettings *InputSettings, theDisplay *Display) (*InputMgr, error) {
+	mgr := &InputMgr{Settings: theSettings, Display: theDisplay, Bindings: make(map[InputAction][]InputBinding), UseMouse: true}
+	for action, names := range theSettings.Bindings {
+		for _, name := range names {
+			binding, err := ParseInputBinding(name)
+			if err != nil {
+				return mgr, fmt.Errorf("binding for %s: %w", action, err)
+			}
+			mgr.Bindings[action] = append(mgr.Bindings[action], binding)
+		}
+	}
+	return mgr, nil
+}
+
+func ParseInputBinding(theName string) (InputBinding, error) {
+	name := strings.ToLower(theName)
+	if binding, found := globalInputNames[name]; found {
+		return binding, nil
+	}
+	if key, found := globalKeyNames[name]; found {
+		return InputBinding{InputSource_Key, key}, nil
+	}
+	if len(name) == 1 && (name[0] >= 'a' && name[0] <= 'z' || name[0] >= '0' && name[0] <= '9') {
+		return InputBinding{InputSource_Key, int32(strings.ToUpper(name)[0])}, nil
+	}
+	return InputBinding{}, fmt.Errorf("unknown input %q", theName)
+}
+
+func (mgr *InputMgr) IsDown(theAction InputAction) bool {
+	for _, binding := range mgr.Bindings[theAction] {
+		switch binding.Source {
+		case InputSource_Key:
+			if rl.IsKeyDown(binding.Code) {
+				return true
+			}
+		case InputSource_Mouse:
+			if rl.IsMouseButtonDown(rl.MouseButton(binding.Code)) {
+				return true
+			}
+		case InputSource_GamepadButton:
+			if rl.IsGamepadButtonDown(mgr.Gamepad, binding.Code) {
+				return true
+			}
+		case InputSource_GamepadAxis:
+			if mgr.TriggerDown[binding.Code] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (mgr *InputMgr) IsPressed(theAction InputAction) bool {
+	for _, binding := range mgr.Bindings[theAction] {
+		switch binding.Source {
+		case InputSource_Key:
+			if rl.IsKeyPressed(binding.Code) {
+				return true
+			}
+		case InputSource_Mouse:
+			if rl.IsMouseButtonPressed(rl.MouseButton(binding.Code)) {
+				return true
+			}
+		case InputSource_GamepadButton:
+			if rl.IsGamepadButtonPressed(mgr.Gamepad, binding.Code) {
+				return true
+			}
+		case InputSource_GamepadAxis:
+			if mgr.TriggerHit[binding.Code] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (mgr *InputMgr) Update(theBoard *Board) {
+	mgr.UpdateTriggers()
+	frog := theBoard.Frog
+
+	mouse_delta := rl.GetMouseDelta()
+	if mouse_delta.X != 0 || mouse_delta.Y != 0 {
+		mgr.UseMouse = true
+	}
+
+	rotate_dir := float32(0)
+	if mgr.IsDown(InputAction_RotateLeft) {
+		rotate_dir--
+	}
+	if mgr.IsDown(InputAction_RotateRight) {
+		rotate_dir++
+	}
+
+	if stick_x, stick_y, ok := mgr.GetStick(); ok {
+		mgr.UseMouse = false
+		mgr.RotateSpeed = 0
+		frog.SetAngle(frog.CalcAngleTo(float32(frog.CenterX)+stick_x, float32(frog.CenterY)+stick_y))
+	} else if rotate_dir != 0 {
+		mgr.UseMouse = false
+		if mgr.RotateSpeed == 0 {
+			mgr.RotateSpeed = mgr.Settings.RotateSpeed
+		} else {
+			mgr.RotateSpeed = min(mgr.RotateSpeed+mgr.Settings.RotateAccel, mgr.Settings.RotateMaxSpeed)
+		}
+		angle := float32(math.Mod(float64(frog.Angle-rotate_dir*mgr.RotateSpeed), 2*math.Pi))
+		frog.SetAngle(angle)
+	} else {
+		mgr.RotateSpeed = 0
+		if mgr.UseMouse {
+			mouse := mgr.Display.GetMousePosition()
+			frog.SetAngle(frog.CalcAngleTo(mouse.X, mouse.Y))
+		}
+	}
+
+	if mgr.IsPressed(InputAction_Mute) {
+		ToggleMute()
+	}
+	if mgr.IsPressed(InputAction_Fire) {
+		theBoard.FireBullet()
+	} else if mgr.IsPressed(InputAction_Swap) {
+		frog.SwapBullets(true)
+	}
+}
+
+// GetStick returns the left stick position once it leaves the dead zone, with the dead zone removed.
+func (mgr *InputMgr) GetStick() (float32, float32, bool) {
+	if !rl.IsGamepadAvailable(mgr.Gamepad) {
+		return 0, 0, false
+	}
+	x := rl.GetGamepadAxisMovement(mgr.Gamepad, rl.GamepadAxisLeftX)
+	y := rl.GetGamepadAxisMovement(mgr.Gamepad, rl.GamepadAxisLeftY)
+	length := float32(math.Sqrt(float64(x*x + y*y)))
+	dead_zone := mgr.Settings.GamepadDeadZone
+	if length <= dead_zone {
+		return 0, 0, false
+	}
+	scale := (min(length, 1) - dead_zone) / (1 - dead_zone) / length
+	return x * scale, y * scale, true
+}
+
+// UpdateTriggers turns the analog triggers into button presses, so they can be bound like any other button.
+func (mgr *InputMgr) UpdateTriggers() {
+	available := rl.IsGamepadAvailable(mgr.Gamepad)
+	for _, axis := range []int32{rl.GamepadAxisLeftTrigger, rl.GamepadAxisRightTrigger} {
+		// triggers rest at -1 and go up to 1 when fully pressed
+		down := available && (rl.GetGamepadAxisMovement(mgr.Gamepad, axis)+1)/2 > mgr.Settings.GamepadTriggerOn
+		mgr.TriggerHit[axis] = down && !mgr.TriggerDown[axis]
+		mgr.TriggerDown[axis] = down
+	}
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/tidwall/gjson"
 )
 
 type LevelParser struct {
-	GraphicsMap map[string]LevelDesc
-	SettingsMap map[string]LevelDescModify
+	GraphicsMap      map[string]LevelDesc
+	SettingsMap      map[string]LevelDescModify
+	StageProgression []StageDesc
 }
 
 func NewLevelParser() LevelParser {
@@ -19,22 +23,48 @@ func NewLevelParser() LevelParser {
 	}
 }
 
-func (parser *LevelParser) ParseLevels(filePath string) {
-	raw, _ := os.ReadFile(filePath)
+// ParseLevels reads the graphics, settings and stage progressions of a level file. Broken entries are skipped,
+// the returned error names each of them by file and id.
+func (parser *LevelParser) ParseLevels(filePath string) error {
+	raw, err := globalVFS.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
 	json := string(raw)
+	if !gjson.Valid(json) {
+		return fmt.Errorf("%s: invalid json", filePath)
+	}
+	var errs []error
 
 	{
 		graphic_list := gjson.Get(json, "Graphics").Array()
+		if len(graphic_list) == 0 {
+			errs = append(errs, fmt.Errorf("%s: no graphics", filePath))
+		}
 		for i := range graphic_list {
 			obj := graphic_list[i].Map()
 			id := obj["id"].String()
+			if id == "" {
+				errs = append(errs, fmt.Errorf("%s: graphics %d: missing id", filePath, i))
+				continue
+			}
+			if _, found := parser.GraphicsMap[id]; found {
+				errs = append(errs, fmt.Errorf("%s: graphics %q: duplicate id", filePath, id))
+				continue
+			}
 			desc := NewLevelDesc()
+			desc.Name = id
 			desc.FrogX = int32(obj["frogx"].Int())
 			desc.FrogY = int32(obj["frogy"].Int())
 			TryGetAndSet(obj, "space", func(r gjson.Result) { desc.IsInSpace = r.Bool() })
 			TryGetAndSet(obj, "dispname", func(r gjson.Result) { desc.DisplayName = r.String() })
+			TryGetAndSet(obj, "theme", func(r gjson.Result) { desc.Theme = r.String() })
 
 			curve_ids := obj["curves"].Array()
+			if len(curve_ids) == 0 {
+				errs = append(errs, fmt.Errorf("%s: graphics %q: no curves", filePath, id))
+				continue
+			}
 			desc.CurveDescs = make([]CurveDesc, len(curve_ids))
 			for k := range curve_ids {
 				desc.CurveDescs[k] = NewCurveDesc()
@@ -59,7 +89,11 @@ func (parser *LevelParser) ParseLevels(filePath string) {
 					delete(iter_item, "y")
 					// dist1 dist2
 					for m := range iter_item {
-						index, _ := strconv.Atoi(m[4:])
+						index, err := strconv.Atoi(strings.TrimPrefix(m, "dist"))
+						if err != nil || index < 1 || index > len(desc.CurveDescs) {
+							errs = append(errs, fmt.Errorf("%s: graphics %q: treasure point %d: unknown key %q", filePath, id, k, m))
+							continue
+						}
 						widen_for_index(&the_point.CurveDist, index)
 						the_point.CurveDist[index-1] = int32(iter_item[m].Int())
 					}
@@ -85,8 +119,8 @@ func (parser *LevelParser) ParseLevels(filePath string) {
 					sprite.ImagePath = iter_item["image"].String()
 					sprite.X = int32(iter_item["x"].Int())
 					sprite.Y = int32(iter_item["y"].Int())
-					TryGetAndSet(obj, "vx", func(r gjson.Result) { sprite.VX = float32(r.Float()) })
-					TryGetAndSet(obj, "vy", func(r gjson.Result) { sprite.VY = float32(r.Float()) })
+					TryGetAndSet(iter_item, "vx", func(r gjson.Result) { sprite.VX = float32(r.Float()) })
+					TryGetAndSet(iter_item, "vy", func(r gjson.Result) { sprite.VY = float32(r.Float()) })
 					desc.BackgroundAlphas = append(desc.BackgroundAlphas, sprite)
 				}
 			})
@@ -97,6 +131,11 @@ func (parser *LevelParser) ParseLevels(filePath string) {
 		settings_list := gjson.Get(json, "Settings").Array()
 		for i := range settings_list {
 			obj := settings_list[i].Map()
+			id := obj["id"].String()
+			if id == "" {
+				errs = append(errs, fmt.Errorf("%s: settings %d: missing id", filePath, i))
+				continue
+			}
 			desc := &LevelDescModify{}
 			desc.CurveDesc = NewCurveDesc()
 			curve_desc := &desc.CurveDesc
@@ -128,9 +167,56 @@ func (parser *LevelParser) ParseLevels(filePath string) {
 				desc.ParTime = 5 * ((desc.ParTime + 4) / 5)
 			}
 
-			parser.SettingsMap[obj["id"].String()] = *desc
+			parser.SettingsMap[id] = *desc
+		}
+	}
+	{
+		stages := gjson.Get(json, "StageProgressions").Map()
+		parser.StageProgression = nil
+		for i := 1; ; i++ {
+			graphics, found := stages["stage"+strconv.Itoa(i)]
+			if !found {
+				break
+			}
+			graphics_ids := strings.Split(graphics.String(), ",")
+			settings_ids := strings.Split(stages["diffi"+strconv.Itoa(i)].String(), ",")
+			if len(graphics_ids) != len(settings_ids) {
+				errs = append(errs, fmt.Errorf("%s: stage %d: %d graphics but %d settings", filePath, i, len(graphics_ids), len(settings_ids)))
+			}
+			stage := StageDesc{Stage: int32(i)}
+			for k := range min(len(graphics_ids), len(settings_ids)) {
+				level := StageLevel{strings.TrimSpace(graphics_ids[k]), strings.TrimSpace(settings_ids[k])}
+				if _, found := parser.MakeLevel(level.GraphicsId, level.SettingsId); !found {
+					errs = append(errs, fmt.Errorf("%s: stage %d: unknown graphics %q or settings %q", filePath, i, level.GraphicsId, level.SettingsId))
+					continue
+				}
+				stage.Levels = append(stage.Levels, level)
+			}
+			parser.StageProgression = append(parser.StageProgression, stage)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (parser *LevelParser) MakeLevel(theGraphicsId, theSettingsId string) (*LevelDesc, bool) {
+	graphics, found := parser.GraphicsMap[theGraphicsId]
+	if !found {
+		return nil, false
+	}
+	desc := graphics
+	desc.CurveDescs = slices.Clone(graphics.CurveDescs)
+	desc.TreasurePoints = slices.Clone(graphics.TreasurePoints)
+	desc.Sprites = slices.Clone(graphics.Sprites)
+	desc.BackgroundAlphas = slices.Clone(graphics.BackgroundAlphas)
+	if theSettingsId != "" {
+		settings, found := parser.SettingsMap[theSettingsId]
+		if !found {
+			return nil, false
 		}
+		desc.ApplySettings(&settings)
+		desc.SettingsId = theSettingsId
 	}
+	return &desc, true
 }
 
 func TryGetAndSet(jobject map[string]gjson.Result, key string, invoke func(gjson.Result)) {
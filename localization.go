@@ -0,0 +1,316 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const LanguageDir string = "./lang"
+const DefaultLanguage string = "en"
+
+// PluralRule picks the plural category of a count, the rules follow the CLDR categories.
+type PluralRule string
+
+const (
+	PluralRule_OneOther PluralRule = "one_other" // English, German, ...
+	PluralRule_French   PluralRule = "french"    // 0 and 1 are singular
+	PluralRule_None     PluralRule = "none"      // Chinese, Japanese, ...
+	PluralRule_Slavic   PluralRule = "slavic"    // Russian, Ukrainian, ...
+	PluralRule_Polish   PluralRule = "polish"
+)
+
+// Catalog holds the strings of one language. Entries are either a format string or an object
+// of plural forms keyed by "=N" for exact counts or by the categories zero, one, two, few, many and other.
+type Catalog struct {
+	Language, Name string
+	Plural         PluralRule
+	Strings        map[string]gjson.Result
+	Fonts          map[FontType]BitmapFont
+	FontImages     []string
+}
+
+var globalCatalog *Catalog = nil
+var globalDefaultCatalog *Catalog = nil
+
+func GetLanguageNames() []string {
+	entries, _ := globalVFS.ReadDir(LanguageDir)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, found := strings.CutSuffix(entry.Name(), ".json"); found && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// LoadCatalog reads lang/<language>.json. Fonts listed in it replace the default ones,
+// which lets languages bring glyphs the default fonts lack.
+func LoadCatalog(theLanguage string) (*Catalog, error) {
+	source := path.Join(LanguageDir, theLanguage+".json")
+	raw, err := globalVFS.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return nil, fmt.Errorf("%s: invalid json", source)
+	}
+
+	catalog := &Catalog{
+		Language: theLanguage,
+		Name:     gjson.Get(json, "name").String(),
+		Plural:   PluralRule(gjson.Get(json, "plural").String()),
+		Strings:  gjson.Get(json, "strings").Map(),
+		Fonts:    make(map[FontType]BitmapFont),
+	}
+	var errs []error
+	switch catalog.Plural {
+	case "":
+		catalog.Plural = PluralRule_OneOther
+	case PluralRule_OneOther, PluralRule_French, PluralRule_None, PluralRule_Slavic, PluralRule_Polish:
+	default:
+		errs = append(errs, fmt.Errorf("%s: unknown plural rule %q", source, catalog.Plural))
+		catalog.Plural = PluralRule_OneOther
+	}
+
+	for name, value := range gjson.Get(json, "fonts").Map() {
+		font_type, found := globalFontNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown font %q", source, name))
+			continue
+		}
+		font, err := LoadBitmapFont(path.Join(LanguageDir, value.String()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: font %q: %w", source, name, err))
+			continue
+		}
+		catalog.Fonts[font_type] = font
+		catalog.FontImages = append(catalog.FontImages, catalog.Fonts[font_type].GetImagePaths()...)
+	}
+	return catalog, errors.Join(errs...)
+}
+
+// InitLocalization loads the default language, which is used for any string another language is missing.
+func InitLocalization() error {
+	catalog, err := LoadCatalog(DefaultLanguage)
+	if catalog == nil {
+		return err
+	}
+	globalDefaultCatalog = catalog
+	globalCatalog = catalog
+	return err
+}
+
+// ApplyLanguage switches the catalog and the fonts that come with it.
+func ApplyLanguage(theLanguage string) error {
+	if globalCatalog != nil && globalCatalog.Language == theLanguage {
+		return nil
+	}
+	catalog := globalDefaultCatalog
+	var err error = nil
+	if theLanguage != DefaultLanguage {
+		if catalog, err = LoadCatalog(theLanguage); catalog == nil {
+			return err
+		}
+	}
+	UnloadLanguageFonts()
+	globalCatalog = catalog
+	RefreshFonts()
+	if globalBoard != nil {
+		globalBoard.LivesText, globalBoard.LevelNameText = "", ""
+	}
+	return err
+}
+
+func UnloadLanguageFonts() {
+	if globalCatalog == nil || globalCatalog == globalDefaultCatalog {
+		return
+	}
+	defaults := getDefaultFontImages()
+	for _, image_path := range globalCatalog.FontImages {
+		if !slices.Contains(defaults, image_path) {
+			UnloadGameTexture(gFontTextures[image_path])
+			delete(gFontTextures, image_path)
+		}
+	}
+	clear(globalCatalog.Fonts)
+}
+
+// GetPluralCategory returns the CLDR plural category of theCount for a rule.
+func GetPluralCategory(theRule PluralRule, theCount int64) string {
+	n := max(theCount, -theCount)
+	switch theRule {
+	case PluralRule_None:
+		return "other"
+	case PluralRule_French:
+		if n <= 1 {
+			return "one"
+		}
+	case PluralRule_Slavic:
+		if n%10 == 1 && n%100 != 11 {
+			return "one"
+		} else if n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) {
+			return "few"
+		}
+		return "many"
+	case PluralRule_Polish:
+		if n == 1 {
+			return "one"
+		} else if n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14) {
+			return "few"
+		}
+		return "many"
+	default:
+		if n == 1 {
+			return "one"
+		}
+	}
+	return "other"
+}
+
+func lookupString(theKey string) (gjson.Result, *Catalog) {
+	for _, catalog := range [2]*Catalog{globalCatalog, globalDefaultCatalog} {
+		if catalog == nil {
+			continue
+		}
+		if value, found := catalog.Strings[theKey]; found {
+			return value, catalog
+		}
+	}
+	return gjson.Result{}, nil
+}
+
+// Tr formats the string of theKey in the current language. The format uses fmt verbs,
+// so translations can reorder arguments with explicit indexes like %[2]d.
+// Missing keys come out as the key itself.
+func Tr(theKey string, args ...any) string {
+	value, catalog := lookupString(theKey)
+	if catalog == nil {
+		return theKey
+	}
+	if value.IsObject() {
+		value = value.Get("other")
+	}
+	return formatString(value.String(), args)
+}
+
+// TrN is Tr for strings with plural forms, theCount selects the form.
+func TrN(theKey string, theCount int64, args ...any) string {
+	value, catalog := lookupString(theKey)
+	if catalog == nil {
+		return theKey
+	}
+	return formatString(selectPluralForm(value, catalog, theCount).String(), args)
+}
+
+// selectPluralForm picks the form of theCount from a string with plural forms: the exact "=N" form,
+// then the plural category, then "other". A plain format string is returned as it is.
+func selectPluralForm(theValue gjson.Result, theCatalog *Catalog, theCount int64) gjson.Result {
+	if !theValue.IsObject() {
+		return theValue
+	}
+	category := GetPluralCategory(theCatalog.Plural, theCount)
+	var exact, by_category, other gjson.Result
+	theValue.ForEach(func(theName, theForm gjson.Result) bool {
+		name := theName.String()
+		if count, found := strings.CutPrefix(name, "="); found {
+			if n, err := strconv.ParseInt(count, 10, 64); err == nil && n == theCount {
+				exact = theForm
+			}
+		} else if name == category {
+			by_category = theForm
+		} else if name == "other" {
+			other = theForm
+		}
+		return true
+	})
+	if exact.Exists() {
+		return exact
+	}
+	if by_category.Exists() {
+		return by_category
+	}
+	return other
+}
+
+func formatString(theFormat string, args []any) string {
+	if len(args) == 0 {
+		return theFormat
+	}
+	return fmt.Sprintf(theFormat, args...)
+}
+
+// AppendTr appends the string of theKey in the current language to theBuffer. It is Tr for integer
+// arguments that does not allocate once theBuffer has grown, for texts made while the game runs.
+func AppendTr(theBuffer []byte, theKey string, args ...int64) []byte {
+	value, catalog := lookupString(theKey)
+	if catalog == nil {
+		return append(theBuffer, theKey...)
+	}
+	if value.IsObject() {
+		value = value.Get("other")
+	}
+	return appendFormat(theBuffer, value.String(), args)
+}
+
+// AppendTrN is AppendTr for strings with plural forms, theCount selects the form.
+func AppendTrN(theBuffer []byte, theKey string, theCount int64, args ...int64) []byte {
+	value, catalog := lookupString(theKey)
+	if catalog == nil {
+		return append(theBuffer, theKey...)
+	}
+	return appendFormat(theBuffer, selectPluralForm(value, catalog, theCount).String(), args)
+}
+
+// appendFormat replaces the %d verbs of theFormat, also indexed ones like %[2]d, with args the way
+// fmt does and appends the result. %% is a percent sign, other verbs are copied unchanged.
+func appendFormat(theBuffer []byte, theFormat string, args []int64) []byte {
+	next_arg := 0
+	for len(theFormat) > 0 {
+		start := strings.IndexByte(theFormat, '%')
+		if start < 0 {
+			return append(theBuffer, theFormat...)
+		}
+		theBuffer = append(theBuffer, theFormat[:start]...)
+		verb := theFormat[start+1:]
+		arg := next_arg
+		if strings.HasPrefix(verb, "[") {
+			index, rest, found := strings.Cut(verb[1:], "]")
+			if n, err := strconv.Atoi(index); found && err == nil {
+				arg, verb = n-1, rest
+			}
+		}
+		switch {
+		case strings.HasPrefix(verb, "%"):
+			theBuffer = append(theBuffer, '%')
+		case strings.HasPrefix(verb, "d") && arg >= 0 && arg < len(args):
+			theBuffer = strconv.AppendInt(theBuffer, args[arg], 10)
+			next_arg = arg + 1
+		case strings.HasPrefix(verb, "d"):
+			theBuffer = append(theBuffer, "%!d(MISSING)"...)
+		default:
+			theBuffer = append(theBuffer, '%')
+			theFormat = theFormat[start+1:]
+			continue
+		}
+		theFormat = verb[1:]
+	}
+	return theBuffer
+}
+
+// GetLevelDisplayName returns the translated name of a level, falling back to the name in the level file.
+func GetLevelDisplayName(theDesc *LevelDesc) string {
+	if _, catalog := lookupString("level." + theDesc.Name); catalog != nil {
+		return Tr("level." + theDesc.Name)
+	}
+	if theDesc.DisplayName != "" {
+		return theDesc.DisplayName
+	}
+	return theDesc.Name
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// InitLogger sends the structured log to stderr, theVerbose also lets the debug records through.
+func InitLogger(theVerbose bool) {
+	level := slog.LevelInfo
+	if theVerbose {
+		level = slog.LevelDebug
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
+
+// SplitErrors flattens joined errors into their parts, so every problem is logged and listed on its own.
+func SplitErrors(theErr error) []error {
+	if theErr == nil {
+		return nil
+	}
+	joined, ok := theErr.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{theErr}
+	}
+	var errs []error
+	for _, err := range joined.Unwrap() {
+		errs = append(errs, SplitErrors(err)...)
+	}
+	return errs
+}
+
+// LogErrors logs each part of theErr as a record of its own.
+func LogErrors(theLevel slog.Level, theMsg string, theErr error, args ...any) {
+	for _, err := range SplitErrors(theErr) {
+		slog.Log(context.Background(), theLevel, theMsg, append(args, "err", err)...)
+	}
+}
@@ -1,7 +1,8 @@
 package main
 
 import (
-	"math"
+	"log/slog"
+	"os"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -11,89 +12,138 @@ const GameWidth int32 = 640
 const GameHeight int32 = 480
 const MaxGapSize int32 = 300
 const MaxPriority int32 = 5
+const TargetFPS int32 = 100
+const LevelsPath string = "./levels/levels.json"
+const FirstLevelId string = "serpents"
 
 var globalBallBlink bool = false
+var globalHeadless bool = false
 
 var globalBoard *Board = nil
 
 func main() {
+	os.Exit(RunCommand(os.Args[1:]))
+}
+
+// RunGame opens the window and plays until it is closed.
+func RunGame(theOptions *GameOptions) error {
+	settings, err := LoadSettings(SettingsPath)
+	if err != nil {
+		slog.Warn("using default settings", "file", SettingsPath, "err", err)
+	}
+	globalSettings = settings
+	if theOptions.Width > 0 {
+		globalSettings.Display.Width, globalSettings.Display.Height = theOptions.Width, theOptions.Height
+	}
+	if theOptions.Fullscreen != nil {
+		globalSettings.Display.Fullscreen = *theOptions.Fullscreen
+	}
+	if theOptions.Mute != nil {
+		globalSettings.Audio.Muted = *theOptions.Mute
+	}
+	if theOptions.Seed != 0 {
+		SeedRandom(theOptions.Seed)
+	}
+
 	rl.InitAudioDevice()
-	rl.InitWindow(GameWidth, GameHeight, "Zuma not Deluxe")
+	InitWindow(&globalSettings.Display, "Zuma not Deluxe")
 	defer rl.CloseWindow()
-	rl.SetTargetFPS(100)
+	rl.SetTargetFPS(TargetFPS)
+	display := NewDisplay(&globalSettings.Display)
+
+	diagnostics := &Diagnostics{}
+	diagnostics.Add(SettingsPath, ApplyBallPalette(globalSettings.Accessibility.BallPalette))
+	input_mgr, err := NewInputMgr(&globalSettings.Input, display)
+	diagnostics.Add(SettingsPath, err)
 
-	InitGlobalTextures()
-	InitGlobalSounds()
-	InitFonts()
+	diagnostics.Add("textures", InitGlobalTextures())
+	diagnostics.Add("sounds", InitGlobalSounds())
+	if err := InitFonts(); err != nil {
+		if _, found := gDefaultFonts[FontType_Float]; found {
+			diagnostics.Add("fonts", err)
+		} else {
+			diagnostics.AddFatal("fonts", err)
+		}
+	}
+	diagnostics.Add("localization", InitLocalization())
+	diagnostics.Add("localization", ApplyLanguage(globalSettings.Language))
 	globalBoard = NewBoard()
 
 	level_parser := NewLevelParser()
-	level_parser.ParseLevels("./levels/levels.json")
-
-	globalBoard.ScoreTarget = 1000
-	globalBoard.TargetBarSize = 256
-	base_desc := level_parser.GraphicsMap["serpents"]
-	globalBoard.LevelDesc = &base_desc
-	globalBoard.SpriteMgr.BackgroundImage = rl.LoadTexture("./levels/serpents/" + base_desc.ImagePath + ".jpg")
-	rl.SetTextureFilter(globalBoard.SpriteMgr.BackgroundImage, rl.FilterTrilinear)
-	defer rl.UnloadTexture(globalBoard.SpriteMgr.BackgroundImage)
-	globalBoard.SpriteMgr.SetupLevel(&base_desc)
-	globalBoard.CurveList = make([]Curve, 2)
-	globalBoard.CurveList[0] = Curve{Board: globalBoard, WayPointMgr: new(WayPointMgr), CurveIndex: 0}
-	globalBoard.CurveList[1] = Curve{Board: globalBoard, WayPointMgr: new(WayPointMgr), CurveIndex: 1}
-	globalBoard.CurveList[0].SetupLevel(globalBoard.LevelDesc, globalBoard.SpriteMgr, 0)
-	globalBoard.CurveList[1].SetupLevel(globalBoard.LevelDesc, globalBoard.SpriteMgr, 1)
-
-	globalBoard.StartLevel()
-	for i := range globalBoard.CurveList {
-		globalBoard.CurveList[i].StartLevel()
-		globalBoard.CurveList[i].DangerPoint = globalBoard.CurveList[i].WayPointMgr.GetNumPoints() - globalBoard.CurveList[i].CurveDesc.DangerDistance
+	diagnostics.Add("levels", level_parser.ParseLevels(LevelsPath))
+	console := NewConsole(globalBoard, &level_parser)
+	console.InputMgr = input_mgr
+	console.Display = display
+	bot := NewBot(globalBoard)
+	recorder := NewRecorder(&globalSettings.Capture, display, console)
+
+	music_config, err := LoadMusicConfig(MusicConfigPath)
+	diagnostics.Add("music", err)
+	music_mgr := NewMusicMgr(music_config, level_parser.StageProgression)
+
+	session := NewSession(theOptions.Mode, globalBoard, &level_parser)
+	if err := theOptions.Level.Start(session); globalBoard.LevelDesc == nil {
+		diagnostics.AddFatal("levels", err)
+	} else {
+		diagnostics.Add("levels", err)
+	}
+	running := diagnostics.Show(display)
+	var hot_reloader *HotReloader = nil
+	if theOptions.Watch {
+		hot_reloader = NewHotReloader(globalBoard, &level_parser)
 	}
 
-	for !rl.WindowShouldClose() {
-		mouse_x, mouse_y := rl.GetMouseX(), rl.GetMouseY()
-		from_center_x := mouse_x - globalBoard.Frog.CenterX
-		var angle float32
-		if from_center_x != 0 {
-			tmp := float32(math.Atan(float64(globalBoard.Frog.CenterY-mouse_y) / float64(from_center_x)))
-			if from_center_x < 0 {
-				tmp += math.Pi
-			}
-			angle = tmp + math.Pi/2.0
-		} else {
-			angle = 0.0
-			if mouse_y < globalBoard.Frog.CenterY {
-				angle = math.Pi
-			}
+	for running && !rl.WindowShouldClose() {
+		if hot_reloader != nil {
+			hot_reloader.Poll()
+		}
+		display.Update()
+		console.Update()
+		if !console.IsOpen && !console.AutoPlay {
+			input_mgr.Update(globalBoard)
 		}
-		globalBoard.Frog.SetAngle(angle)
-
-		// Mouse Events
-		if rl.IsMouseButtonPressed(rl.MouseButtonLeft) {
-			can_fire := true
-			for i := range globalBoard.CurveList {
-				if !globalBoard.CurveList[i].CanFire() {
-					can_fire = false
-					break
-				}
+
+		if console.ShouldUpdateBoard() {
+			if console.AutoPlay {
+				bot.Play()
 			}
-			if can_fire && globalBoard.Frog.StartFire(true) {
-				rl.PlaySound(gSounds[Sound_FrogFire])
+			globalBoard.Update()
+			if err := session.Update(); err != nil {
+				LogErrors(slog.LevelWarn, "level incomplete", err)
 			}
-		} else if rl.IsMouseButtonPressed(rl.MouseButtonRight) {
-			globalBoard.Frog.SwapBullets(true)
 		}
-
-		globalBoard.Update()
+		music_mgr.Update(globalBoard)
 
 		// Update Foreground
-		rl.BeginDrawing()
+		display.Begin()
 		globalBoard.Draw()
-		rl.EndDrawing()
+		console.Draw()
+		display.End()
+		recorder.Update(input_mgr, globalBoard)
+	}
+	recorder.Wait()
+	display.StoreWindowSize()
+	// the choices of the command line only hold for this run
+	if theOptions.Width > 0 {
+		globalSettings.Display.Width, globalSettings.Display.Height = settings.Display.Width, settings.Display.Height
+	}
+	if theOptions.Fullscreen != nil {
+		globalSettings.Display.Fullscreen = settings.Display.Fullscreen
+	}
+	if theOptions.Mute != nil {
+		globalSettings.Audio.Muted = settings.Audio.Muted
+	}
+	if err := globalSettings.Save(SettingsPath); err != nil {
+		slog.Error("cannot save the settings", "file", SettingsPath, "err", err)
 	}
+	display.Destroy()
+	music_mgr.Destroy()
+	globalBoard.LevelScope.Release()
 	globalBoard.SoundMgr.Destroy()
 	DestroyFontTextures()
 	DestroyGlobalSounds()
 	DestroyGlobalTextures()
 	rl.CloseAudioDevice()
+	globalAssets.ReportLeaks()
+	return nil
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+type AudioBus int32
+
+const (
+	AudioBus_Master AudioBus = iota
+	AudioBus_Music
+	AudioBus_Sfx
+	AudioBus_Ui
+	AudioBus_Max
+)
+
+var globalAudioBusNames [AudioBus_Max]string = [AudioBus_Max]string{"master", "music", "sfx", "ui"}
+
+// MaxSoundAliases is the number of copies of a sound that can play at the same time,
+// each copy has its own volume, pitch and pan.
+const MaxSoundAliases int = 4
+
+type SoundInfo struct {
+	Volume float32
+	Bus    AudioBus
+}
+
+var gSoundInfo map[SoundKey]SoundInfo = make(map[SoundKey]SoundInfo)
+var gSoundAliases map[SoundKey][]rl.Sound = make(map[SoundKey][]rl.Sound)
+var gSoundAliasNext map[SoundKey]int = make(map[SoundKey]int)
+
+func DestroySoundAliases() {
+	for key := range gSoundAliases {
+		for _, alias := range gSoundAliases[key] {
+			rl.UnloadSoundAlias(alias)
+		}
+	}
+	clear(gSoundAliases)
+}
+
+func GetBusVolume(theBus AudioBus) float32 {
+	settings := &globalSettings.Audio
+	if settings.Muted {
+		return 0
+	}
+	if theBus == AudioBus_Master {
+		return settings.Volumes["master"]
+	}
+	return settings.Volumes["master"] * settings.Volumes[globalAudioBusNames[theBus]]
+}
+
+// GetSoundAlias returns a copy of the sound that is not playing, so changing its volume, pitch or pan
+// does not touch the shared entry in gSounds. When all copies are busy the oldest one is cut off.
+func GetSoundAlias(theKey SoundKey) rl.Sound {
+	aliases := gSoundAliases[theKey]
+	for _, alias := range aliases {
+		if !rl.IsSoundPlaying(alias) {
+			return alias
+		}
+	}
+	if len(aliases) < MaxSoundAliases {
+		alias := rl.LoadSoundAlias(gSounds[theKey])
+		gSoundAliases[theKey] = append(aliases, alias)
+		return alias
+	}
+	index := gSoundAliasNext[theKey] % len(aliases)
+	gSoundAliasNext[theKey] = index + 1
+	rl.StopSound(aliases[index])
+	return aliases[index]
+}
+
+// GetPanForX maps a position on the board to a stereo pan, keeping a bit of both channels at the edges.
+func GetPanForX(theX float32) float32 {
+	pan := theX/float32(GameWidth)*2 - 1
+	return min(max(pan, -1), 1) * 0.8
+}
+
+func ParseAudioBus(theName string) (AudioBus, error) {
+	for i := range AudioBus_Max {
+		if globalAudioBusNames[i] == theName {
+			return i, nil
+		}
+	}
+	return AudioBus_Max, fmt.Errorf("unknown bus %q", theName)
+}
+
+// PlayGameSound plays a sound through its bus. theVolume scales the volume the sound was registered with,
+// thePitchShift is in semitones and thePan is 0 for the center.
+func PlayGameSound(theKey SoundKey, theVolume, thePan, thePitchShift float32) {
+	if globalHeadless {
+		return
+	}
+	if _, found := gSounds[theKey]; !found {
+		return
+	}
+	info := gSoundInfo[theKey]
+	volume := theVolume * info.Volume * GetBusVolume(info.Bus)
+	if volume <= 0 {
+		return
+	}
+	alias := GetSoundAlias(theKey)
+	rl.SetSoundVolume(alias, volume)
+	rl.SetSoundPitch(alias, float32(math.Pow(1.0594630943592952645618252949463, float64(thePitchShift))))
+	rl.SetSoundPan(alias, 0.5-thePan/2)
+	rl.PlaySound(alias)
+}
+
+func SetBusVolume(theBus AudioBus, theVolume float32) {
+	globalSettings.Audio.Volumes[globalAudioBusNames[theBus]] = min(max(theVolume, 0), 1)
+}
+
+func ToggleMute() {
+	globalSettings.Audio.Muted = !globalSettings.Audio.Muted
+}
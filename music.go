@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/tidwall/gjson"
+)
+
+const MusicConfigPath string = "./sounds/music.json"
+
+type MusicEntry struct {
+	Track, Tension string
+}
+
+// MusicConfig is read from sounds/music.json: "level" plays by default, entries in "stages" by stage number
+// and in "graphics" by graphics id win over it, each with an optional "tension" layer. "victory" plays once
+// a board is cleared and "menu" is kept for menu screens, which the game does not have yet.
+type MusicConfig struct {
+	Crossfade    float32
+	TensionPitch float32
+	Menu         string
+	Victory      string
+	Level        MusicEntry
+	Stages       map[int32]MusicEntry
+	Graphics     map[string]MusicEntry
+}
+
+type MusicTrack struct {
+	Path          string
+	Music         rl.Music
+	Data          []byte // the file the stream decodes from
+	Volume        float32
+	TargetVolume  float32
+	UnloadOnFaded bool
+}
+
+type MusicMode int32
+
+const (
+	MusicMode_None MusicMode = iota
+	MusicMode_Menu
+	MusicMode_Level
+	MusicMode_Victory
+)
+
+// MusicMgr streams the background music. Only one main track plays at a time, switching tracks cross-fades
+// between them. A level can have a tension layer that plays in sync with its track and is faded in as the
+// balls approach the hole, without a tension layer the track speeds up instead.
+type MusicMgr struct {
+	Config       MusicConfig
+	StageOf      map[string]int32
+	Mode         MusicMode
+	LevelName    string
+	Current      *MusicTrack
+	Tension      *MusicTrack
+	FadingTracks []*MusicTrack
+	Volume       float32
+}
+
+func NewMusicConfig() MusicConfig {
+	return MusicConfig{
+		Crossfade:    2,
+		TensionPitch: 0.06,
+		Stages:       make(map[int32]MusicEntry),
+		Graphics:     make(map[string]MusicEntry),
+	}
+}
+
+func parseMusicEntry(theResult gjson.Result) MusicEntry {
+	return MusicEntry{theResult.Get("track").String(), theResult.Get("tension").String()}
+}
+
+func LoadMusicConfig(filePath string) (MusicConfig, error) {
+	config := NewMusicConfig()
+	raw, err := globalVFS.ReadFile(filePath)
+	if err != nil {
+		return config, err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return config, fmt.Errorf("%s: invalid json", filePath)
+	}
+
+	root := gjson.Parse(json).Map()
+	TryGetAndSet(root, "crossfade", func(r gjson.Result) { config.Crossfade = float32(r.Float()) })
+	TryGetAndSet(root, "tension_pitch", func(r gjson.Result) { config.TensionPitch = float32(r.Float()) })
+	config.Menu = gjson.Get(json, "menu").String()
+	config.Victory = gjson.Get(json, "victory").String()
+	config.Level = parseMusicEntry(gjson.Get(json, "level"))
+	for key, value := range gjson.Get(json, "stages").Map() {
+		stage, err := strconv.Atoi(key)
+		if err != nil {
+			return config, fmt.Errorf("%s: invalid stage %q", filePath, key)
+		}
+		config.Stages[int32(stage)] = parseMusicEntry(value)
+	}
+	for key, value := range gjson.Get(json, "graphics").Map() {
+		config.Graphics[key] = parseMusicEntry(value)
+	}
+	return config, nil
+}
+
+func NewMusicMgr(theConfig MusicConfig, theStages []StageDesc) *MusicMgr {
+	mgr := &MusicMgr{Config: theConfig, StageOf: make(map[string]int32), Volume: 1}
+	for _, stage := range theStages {
+		for _, level := range stage.Levels {
+			if _, found := mgr.StageOf[level.GraphicsId]; !found {
+				mgr.StageOf[level.GraphicsId] = stage.Stage
+			}
+		}
+	}
+	return mgr
+}
+
+func (mgr *MusicMgr) Destroy() {
+	for _, track := range mgr.FadingTracks {
+		rl.UnloadMusicStream(track.Music)
+	}
+	for _, track := range []*MusicTrack{mgr.Current, mgr.Tension} {
+		if track != nil {
+			rl.UnloadMusicStream(track.Music)
+		}
+	}
+	mgr.FadingTracks, mgr.Current, mgr.Tension = nil, nil, nil
+}
+
+// GetLevelMusic picks the music of a level: the graphics entry wins over the stage entry,
+// which wins over the default level music.
+func (mgr *MusicMgr) GetLevelMusic(theGraphicsId string) MusicEntry {
+	if entry, found := mgr.Config.Graphics[theGraphicsId]; found {
+		return entry
+	}
+	if stage, found := mgr.StageOf[theGraphicsId]; found {
+		if entry, found := mgr.Config.Stages[stage]; found {
+			return entry
+		}
+	}
+	return mgr.Config.Level
+}
+
+// LoadTrack opens a track, nil when no track is configured or it cannot be loaded, the game then plays without it.
+func (mgr *MusicMgr) LoadTrack(thePath string) *MusicTrack {
+	if thePath == "" {
+		return nil
+	}
+	music, data, err := LoadAssetMusic(thePath)
+	if err != nil {
+		slog.Warn("cannot load a music track", "err", err)
+		return nil
+	}
+	return &MusicTrack{Path: thePath, Music: music, Data: data, TargetVolume: 1}
+}
+
+func (mgr *MusicMgr) FadeOut(theTrack *MusicTrack) {
+	if theTrack == nil {
+		return
+	}
+	theTrack.TargetVolume, theTrack.UnloadOnFaded = 0, true
+	mgr.FadingTracks = append(mgr.FadingTracks, theTrack)
+}
+
+func (mgr *MusicMgr) Play(theMode MusicMode, theEntry MusicEntry) {
+	mgr.Mode = theMode
+	if mgr.Current != nil && mgr.Current.Path == theEntry.Track {
+		return
+	}
+	mgr.FadeOut(mgr.Current)
+	mgr.FadeOut(mgr.Tension)
+	mgr.Current, mgr.Tension = mgr.LoadTrack(theEntry.Track), nil
+	if mgr.Current == nil {
+		return
+	}
+	mgr.StartTrack(mgr.Current)
+	if mgr.Tension = mgr.LoadTrack(theEntry.Tension); mgr.Tension != nil {
+		mgr.Tension.TargetVolume = 0
+		mgr.StartTrack(mgr.Tension)
+	}
+}
+
+func (mgr *MusicMgr) PlayLevel(theGraphicsId string) {
+	mgr.LevelName = theGraphicsId
+	mgr.Play(MusicMode_Level, mgr.GetLevelMusic(theGraphicsId))
+}
+
+func (mgr *MusicMgr) PlayMenu() {
+	mgr.Play(MusicMode_Menu, MusicEntry{Track: mgr.Config.Menu})
+}
+
+func (mgr *MusicMgr) PlayVictory() {
+	mgr.Play(MusicMode_Victory, MusicEntry{Track: mgr.Config.Victory})
+}
+
+func (mgr *MusicMgr) StartTrack(theTrack *MusicTrack) {
+	theTrack.Volume = 0
+	rl.SetMusicVolume(theTrack.Music, 0)
+	rl.PlayMusicStream(theTrack.Music)
+}
+
+// Update follows the state of the board: it switches to the victory music once the board is cleared,
+// back to the level music when a level starts and sets the tension from the ball closest to its hole.
+func (mgr *MusicMgr) Update(theBoard *Board) {
+	if theBoard != nil {
+		victory := theBoard.HasReachedTarget && theBoard.IsBoardCleared()
+		if victory && mgr.Mode == MusicMode_Level {
+			mgr.PlayVictory()
+		} else if !victory && (mgr.Mode != MusicMode_Level || mgr.LevelName != theBoard.LevelDesc.Name) {
+			mgr.PlayLevel(theBoard.LevelDesc.Name)
+		}
+	}
+
+	mgr.Volume = GetBusVolume(AudioBus_Music)
+	var tension float32 = 0
+	if theBoard != nil && mgr.Mode == MusicMode_Level {
+		tension = theBoard.GetTension()
+	}
+	if mgr.Tension != nil {
+		mgr.Tension.TargetVolume = tension
+	} else if mgr.Current != nil {
+		rl.SetMusicPitch(mgr.Current.Music, 1+tension*mgr.Config.TensionPitch)
+	}
+
+	step := 1 / max(mgr.Config.Crossfade*float32(TargetFPS), 1)
+	for _, track := range []*MusicTrack{mgr.Current, mgr.Tension} {
+		if track != nil {
+			mgr.UpdateTrack(track, step)
+		}
+	}
+	for i := len(mgr.FadingTracks) - 1; i >= 0; i-- {
+		track := mgr.FadingTracks[i]
+		mgr.UpdateTrack(track, step)
+		if track.Volume <= 0 {
+			rl.StopMusicStream(track.Music)
+			rl.UnloadMusicStream(track.Music)
+			mgr.FadingTracks = append(mgr.FadingTracks[:i], mgr.FadingTracks[i+1:]...)
+		}
+	}
+}
+
+func (mgr *MusicMgr) UpdateTrack(theTrack *MusicTrack, theStep float32) {
+	if theTrack.Volume < theTrack.TargetVolume {
+		theTrack.Volume = min(theTrack.Volume+theStep, theTrack.TargetVolume)
+	} else if theTrack.Volume > theTrack.TargetVolume {
+		theTrack.Volume = max(theTrack.Volume-theStep, theTrack.TargetVolume)
+	}
+	rl.SetMusicVolume(theTrack.Music, theTrack.Volume*mgr.Volume)
+	rl.UpdateMusicStream(theTrack.Music)
+}
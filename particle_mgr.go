@@ -2,7 +2,6 @@ package main
 
 import (
 	"image/color"
-	"slices"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -13,6 +12,7 @@ type ParticleMgr struct {
 	SparkleList      [MaxPriority + 1][]Sparkle
 	ExplosionList    []Explosion
 	FloatingTextList []FloatingText
+	TextWidths       []int32
 }
 
 type Sparkle struct {
@@ -28,8 +28,9 @@ type Explosion struct {
 	UpdateCnt       int32
 }
 
+// FloatingText owns the buffer of its text, a slot of the list keeps it for the next text added.
 type FloatingText struct {
-	Text                          string
+	Text                          []byte
 	Font                          FontType
 	X, Y                          int32
 	Color                         color.RGBA
@@ -43,22 +44,29 @@ func (mgr *ParticleMgr) AddExplosion(x, y, theRadius int32, theColor color.RGBA,
 	})
 }
 
-func (mgr *ParticleMgr) AddFloatingText(x, y int32, theColor color.RGBA, theText string, theFont FontType, theStagger, theScoreInc, theDuration int32, fade bool) {
-	mgr.FloatingTextList = append(mgr.FloatingTextList, FloatingText{
-		theText, theFont, x, y, theColor, theScoreInc, theDuration, -theStagger, fade,
-	})
+// AddFloatingText copies theText into a free slot of the list, so adding texts does not allocate once the list has grown.
+func (mgr *ParticleMgr) AddFloatingText(x, y int32, theColor color.RGBA, theText []byte, theFont FontType, theStagger, theScoreInc, theDuration int32, fade bool) {
+	if len(mgr.FloatingTextList) == cap(mgr.FloatingTextList) {
+		mgr.FloatingTextList = append(mgr.FloatingTextList, FloatingText{})
+	} else {
+		mgr.FloatingTextList = mgr.FloatingTextList[:len(mgr.FloatingTextList)+1]
+	}
+	target := &mgr.FloatingTextList[len(mgr.FloatingTextList)-1]
+	*target = FloatingText{
+		append(target.Text[:0], theText...), theFont, x, y, theColor, theScoreInc, theDuration, -theStagger, fade,
+	}
 }
 
 func (mgr *ParticleMgr) AddSparkle(x, y, vx, vy float32, thePriority, theDuration, theStagger int32, theColor color.RGBA) {
 	sparkle := Sparkle{}
-	cols := gTextures[Texture_Sparkle].Width / gTextures[Texture_Sparkle].Height
-	sparkle.X = x - float32((gTextures[Texture_Sparkle].Width/cols)/2)
-	sparkle.Y = y - float32(gTextures[Texture_Sparkle].Height/2)
+	cell := GetSprite(Texture_Sparkle).GetCell(0)
+	sparkle.X = x - float32(int32(cell.Width)/2)
+	sparkle.Y = y - float32(int32(cell.Height)/2)
 	sparkle.VX, sparkle.VY = vx, vy
 	if theDuration > 0 {
 		sparkle.Duration = theDuration
 	} else {
-		sparkle.Duration = 2 * cols
+		sparkle.Duration = 2 * gTextureLayouts[Texture_Sparkle].Cols
 	}
 
 	sparkle.Frame = 0
@@ -67,16 +75,45 @@ func (mgr *ParticleMgr) AddSparkle(x, y, vx, vy float32, thePriority, theDuratio
 	mgr.SparkleList[thePriority] = append(mgr.SparkleList[thePriority], sparkle)
 }
 
-func AddTextsToMgr(texts []string, theFont FontType, theMgr *ParticleMgr, theColor color.RGBA, x, y, theStagger, theScoreInc int32) {
-	if len(texts) == 0 {
+// TextList holds texts formatted one after the other into a single reused buffer.
+type TextList struct {
+	Bytes []byte
+	Ends  []int
+}
+
+func (list *TextList) Reset() {
+	list.Bytes, list.Ends = list.Bytes[:0], list.Ends[:0]
+}
+
+// EndText ends the text appended to Bytes since the last one.
+func (list *TextList) EndText() {
+	list.Ends = append(list.Ends, len(list.Bytes))
+}
+
+func (list *TextList) Len() int {
+	return len(list.Ends)
+}
+
+func (list *TextList) Get(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = list.Ends[i-1]
+	}
+	return list.Bytes[start:list.Ends[i]]
+}
+
+func AddTextsToMgr(texts *TextList, theFont FontType, theMgr *ParticleMgr, theColor color.RGBA, x, y, theStagger, theScoreInc int32) {
+	if texts.Len() == 0 {
 		return
 	}
 
 	font := gFonts[theFont]
-	texture := gFontTextures[font.Layers[0].ImageName]
+	texture := gFontTextures[font.Layers[0].ImagePaths[0]]
 	var total_width, total_height int32 = 0, 0
-	for i := range texts {
-		text_width := font.StringWidth(texts[i])
+	theMgr.TextWidths = theMgr.TextWidths[:0]
+	for i := range texts.Len() {
+		text_width := font.BytesWidth(texts.Get(i))
+		theMgr.TextWidths = append(theMgr.TextWidths, text_width)
 		if text_width >= total_width {
 			total_width = text_width
 		}
@@ -96,18 +133,29 @@ func AddTextsToMgr(texts []string, theFont FontType, theMgr *ParticleMgr, theCol
 	if text_x < 40 {
 		text_x = 40
 	}
-	for i := range texts {
-		text_width := font.StringWidth(texts[i])
-		theMgr.AddFloatingText(text_x+(total_width-text_width)/2, text_y, theColor, texts[i], theFont, theStagger, theScoreInc, 100, false)
+	for i := range texts.Len() {
+		text_width := theMgr.TextWidths[i]
+		theMgr.AddFloatingText(text_x+(total_width-text_width)/2, text_y, theColor, texts.Get(i), theFont, theStagger, theScoreInc, 100, false)
 		text_y += texture.Height
 	}
 }
 
+// Reset removes all particles but keeps the memory of the lists for the next level.
+func (mgr *ParticleMgr) Reset() {
+	for i := range mgr.SparkleList {
+		mgr.SparkleList[i] = mgr.SparkleList[i][:0]
+	}
+	mgr.ExplosionList = mgr.ExplosionList[:0]
+	mgr.FloatingTextList = mgr.FloatingTextList[:0]
+}
+
 func (mgr *ParticleMgr) Draw(thePriority int32) {
 	mgr.DrawSparkles(thePriority)
 }
 
+// DrawExplosions draws the flashes first and then all explosion sprites in one additive pass.
 func (mgr *ParticleMgr) DrawExplosions() {
+	num_sprites := 0
 	for i := range mgr.ExplosionList {
 		target := &mgr.ExplosionList[i]
 		if target.UpdateCnt < 0 {
@@ -116,13 +164,25 @@ func (mgr *ParticleMgr) DrawExplosions() {
 		if target.Radius > 0 {
 			rl.DrawCircle(target.X, target.Y, float32(target.CurRadius), target.CurColor)
 		} else {
-			width, height := gTextures[Texture_Explosion].Width, gTextures[Texture_Explosion].Height/17
-			rl.BeginBlendMode(rl.BlendAdditive)
-			rl.DrawTextureRec(gTextures[Texture_Explosion], rect(0, (target.UpdateCnt>>2)*height, width, height),
-				vec2(target.X-width/2, target.Y-height/2), rl.White)
-			rl.EndBlendMode()
+			num_sprites++
 		}
 	}
+	if num_sprites == 0 {
+		return
+	}
+
+	sprite := GetSprite(Texture_Explosion)
+	rl.BeginBlendMode(rl.BlendAdditive)
+	for i := range mgr.ExplosionList {
+		target := &mgr.ExplosionList[i]
+		if target.UpdateCnt < 0 || target.Radius > 0 {
+			continue
+		}
+		cell := sprite.GetCell(target.UpdateCnt >> 2)
+		rl.DrawTextureRec(sprite.Texture, cell,
+			vec2(target.X-int32(cell.Width)/2, target.Y-int32(cell.Height)/2), rl.White)
+	}
+	rl.EndBlendMode()
 }
 
 func (mgr *ParticleMgr) DrawFloatingText() {
@@ -135,23 +195,25 @@ func (mgr *ParticleMgr) DrawFloatingText() {
 		if target.Fade {
 			the_color.A = uint8(255 - 255*target.UpdateCnt/target.Duration)
 		}
-		gFonts[target.Font].DrawText(target.Text, target.X, target.Y, the_color)
+		gFonts[target.Font].DrawBytes(target.Text, target.X, target.Y, the_color)
 	}
 }
 
+// DrawSparkles only switches the blend mode when the layer has sparkles.
 func (mgr *ParticleMgr) DrawSparkles(thePriority int32) {
+	list := mgr.SparkleList[thePriority]
+	if len(list) == 0 {
+		return
+	}
+	sprite := GetSprite(Texture_Sparkle)
 	rl.BeginBlendMode(rl.BlendAdditive)
-	list := &mgr.SparkleList[thePriority]
-	for i := range *list {
-		if (*list)[i].UpdateCnt < 0 {
+	for i := range list {
+		if list[i].UpdateCnt < 0 {
 			continue
 		}
 
-		texture := gTextures[Texture_Sparkle]
-		cols := texture.Width / texture.Height
-		rl.DrawTextureRec(texture,
-			rl.NewRectangle(float32((*list)[i].Frame*texture.Width/cols), 0, float32(texture.Width/cols), float32(texture.Height)),
-			rl.NewVector2((*list)[i].X, (*list)[i].Y), (*list)[i].Color)
+		rl.DrawTextureRec(sprite.Texture, sprite.GetCell(list[i].Frame),
+			rl.NewVector2(list[i].X, list[i].Y), list[i].Color)
 	}
 	rl.EndBlendMode()
 }
@@ -170,17 +232,20 @@ func (mgr *ParticleMgr) Update() bool {
 	return mgr.HadUpdate
 }
 
+// The update functions drop finished particles by moving the live ones forward in a single pass.
 func (mgr *ParticleMgr) UpdateExplosions() {
-	for i := 0; i < len(mgr.ExplosionList); {
+	num_alive := 0
+	for i := range mgr.ExplosionList {
 		target := &mgr.ExplosionList[i]
 		target.UpdateCnt++
-		if target.UpdateCnt < 0 {
-			i++
-			continue
-		}
 		if target.UpdateCnt > 50 {
 			mgr.HadUpdate = true
-			mgr.ExplosionList = slices.Delete(mgr.ExplosionList, i, i+1)
+			continue
+		}
+		mgr.ExplosionList[num_alive] = *target
+		target = &mgr.ExplosionList[num_alive]
+		num_alive++
+		if target.UpdateCnt < 0 {
 			continue
 		}
 
@@ -194,18 +259,15 @@ func (mgr *ParticleMgr) UpdateExplosions() {
 			cur_color = color.RGBA{0, 0, 0, 1}
 		}
 		target.CurColor = cur_color
-		i++
 	}
+	mgr.ExplosionList = mgr.ExplosionList[:num_alive]
 }
 
 func (mgr *ParticleMgr) UpdateFloatingText() {
-	for i := 0; i < len(mgr.FloatingTextList); {
+	num_alive := 0
+	for i := range mgr.FloatingTextList {
 		target := &mgr.FloatingTextList[i]
 		target.UpdateCnt++
-		if target.UpdateCnt < 0 {
-			i++
-			continue
-		}
 		if target.UpdateCnt == 1 {
 			if target.ScoreInc > 0 {
 				mgr.Board.IncScore(target.ScoreInc, true)
@@ -213,37 +275,39 @@ func (mgr *ParticleMgr) UpdateFloatingText() {
 		}
 		if target.UpdateCnt > target.Duration {
 			mgr.HadUpdate = true
-			mgr.FloatingTextList = slices.Delete(mgr.FloatingTextList, i, i+1)
-			i++
 			continue
 		}
-		mgr.HadUpdate = true
-		target.Y--
-		i++
+		if target.UpdateCnt >= 0 {
+			mgr.HadUpdate = true
+			target.Y--
+		}
+		// swapped instead of copied, so every slot keeps a text buffer of its own
+		mgr.FloatingTextList[num_alive], mgr.FloatingTextList[i] = mgr.FloatingTextList[i], mgr.FloatingTextList[num_alive]
+		num_alive++
 	}
+	mgr.FloatingTextList = mgr.FloatingTextList[:num_alive]
 }
 
 func (mgr *ParticleMgr) UpdateSparkles() {
 	for i := range MaxPriority + 1 {
-		list := &mgr.SparkleList[i]
-		for k := 0; k < len(*list); {
-			(*list)[k].UpdateCnt++
-			if (*list)[k].UpdateCnt < 0 {
-				k++
-				continue
-			}
-
-			mgr.HadUpdate = true
-			cols := gTextures[Texture_Sparkle].Width / gTextures[Texture_Sparkle].Height
-			(*list)[k].Frame = ((*list)[k].UpdateCnt >> 1) % cols
-			if (*list)[k].UpdateCnt >= (*list)[k].Duration {
-				*list = slices.Delete(*list, k, k+1)
-				continue
-			} else {
-				(*list)[k].X += (*list)[k].VX
-				(*list)[k].Y += (*list)[k].VY
-				k++
+		list := mgr.SparkleList[i]
+		num_alive := 0
+		for k := range list {
+			sparkle := list[k]
+			sparkle.UpdateCnt++
+			if sparkle.UpdateCnt >= 0 {
+				mgr.HadUpdate = true
+				cols := gTextureLayouts[Texture_Sparkle].Cols
+				sparkle.Frame = (sparkle.UpdateCnt >> 1) % cols
+				if sparkle.UpdateCnt >= sparkle.Duration {
+					continue
+				}
+				sparkle.X += sparkle.VX
+				sparkle.Y += sparkle.VY
 			}
+			list[num_alive] = sparkle
+			num_alive++
 		}
+		mgr.SparkleList[i] = list[:num_alive]
 	}
 }
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// GameMode decides what happens once a level is won or lost.
+type GameMode int32
+
+const (
+	GameMode_Adventure GameMode = iota // the stage progression level after level, a game over starts the stage again
+	GameMode_Gauntlet                  // one level without a score target, the chain keeps coming until it reaches the hole
+	GameMode_Practice                  // one level over and over, without losing lives
+)
+
+var globalGameModeNames map[string]GameMode = map[string]GameMode{
+	"adventure": GameMode_Adventure,
+	"gauntlet":  GameMode_Gauntlet,
+	"practice":  GameMode_Practice,
+}
+
+func ParseGameMode(theName string) (GameMode, error) {
+	mode, found := globalGameModeNames[theName]
+	if !found {
+		return mode, fmt.Errorf("unknown mode %q, expected %s", theName, strings.Join(slices.Sorted(maps.Keys(globalGameModeNames)), ", "))
+	}
+	return mode, nil
+}
+
+// LevelEndDelay is the number of frames the end of a level stays on screen before the next one starts.
+const LevelEndDelay int32 = 3 * TargetFPS
+const StartingLives int32 = 3
+
+// Session plays levels on a board in a game mode. Stage and Level are the position in the stage progression,
+// they are only used by the adventure mode.
+type Session struct {
+	Mode                   GameMode
+	Board                  *Board
+	Parser                 *LevelParser
+	Stage, Level           int
+	GraphicsId, SettingsId string
+	EndCount               int32
+}
+
+func NewSession(theMode GameMode, theBoard *Board, theParser *LevelParser) *Session {
+	return &Session{Mode: theMode, Board: theBoard, Parser: theParser}
+}
+
+// StartLevel starts the level of a graphics and a settings id. An adventure goes on from the place
+// of the level in the stage progression, or from the first stage if the level is not part of it.
+func (session *Session) StartLevel(theGraphicsId, theSettingsId string) error {
+	session.GraphicsId, session.SettingsId = theGraphicsId, theSettingsId
+	session.Stage, session.Level = 0, -1
+	for stage_index, stage := range session.Parser.StageProgression {
+		for level_index, level := range stage.Levels {
+			if level.GraphicsId == theGraphicsId && (theSettingsId == "" || level.SettingsId == theSettingsId) {
+				session.Stage, session.Level = stage_index, level_index
+				session.SettingsId = level.SettingsId
+				return session.Restart()
+			}
+		}
+	}
+	return session.Restart()
+}
+
+// StartStage starts a level of the stage progression, both indices count from 0.
+func (session *Session) StartStage(theStage, theLevel int) error {
+	stages := session.Parser.StageProgression
+	if theStage < 0 || theStage >= len(stages) {
+		return fmt.Errorf("stage %d out of range, the progression has %d stages", theStage+1, len(stages))
+	}
+	if theLevel < 0 || theLevel >= len(stages[theStage].Levels) {
+		return fmt.Errorf("level %d out of range, stage %d has %d levels", theLevel+1, theStage+1, len(stages[theStage].Levels))
+	}
+	session.Stage, session.Level = theStage, theLevel
+	level := stages[theStage].Levels[theLevel]
+	session.GraphicsId, session.SettingsId = level.GraphicsId, level.SettingsId
+	return session.Restart()
+}
+
+// Restart sets the current level up again, the score and the lives are kept.
+func (session *Session) Restart() error {
+	desc, found := session.Parser.MakeLevel(session.GraphicsId, session.SettingsId)
+	if !found {
+		return fmt.Errorf("unknown graphics %q or settings %q", session.GraphicsId, session.SettingsId)
+	}
+	session.EndCount = 0
+	session.Board.IsEndless = session.Mode == GameMode_Gauntlet
+	return session.Board.LoadLevel(desc)
+}
+
+// advance moves to the next level of the stage progression, after the last one the game starts over.
+func (session *Session) advance() {
+	stages := session.Parser.StageProgression
+	if len(stages) == 0 {
+		return
+	}
+	session.Level++
+	if session.Level >= len(stages[session.Stage].Levels) {
+		session.Stage, session.Level = (session.Stage+1)%len(stages), 0
+	}
+	level := stages[session.Stage].Levels[session.Level]
+	session.GraphicsId, session.SettingsId = level.GraphicsId, level.SettingsId
+}
+
+// Update follows the board once it was updated. A finished level stays on screen for LevelEndDelay frames,
+// then a won level moves on and a lost one starts again.
+func (session *Session) Update() error {
+	board := session.Board
+	won := board.HasReachedTarget && board.IsBoardCleared()
+	if !won && !board.HasBallReachedHole() {
+		return nil
+	}
+	session.EndCount++
+	if session.EndCount < LevelEndDelay {
+		return nil
+	}
+
+	if won {
+		if session.Mode == GameMode_Adventure {
+			session.advance()
+		}
+		return session.Restart()
+	}
+	switch session.Mode {
+	case GameMode_Adventure:
+		board.Lives--
+		if board.Lives <= 0 {
+			board.Lives, board.Score, board.ScoreDisplay = StartingLives, 0, 0
+			if session.Level > 0 {
+				session.Level = -1
+				session.advance()
+			}
+			return session.Restart()
+		}
+	case GameMode_Gauntlet:
+		board.LevelBeginScore = 0
+	}
+	board.Score, board.ScoreDisplay = board.LevelBeginScore, board.LevelBeginScore
+	return session.Restart()
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+)
+
+const SettingsPath string = "./settings.json"
+
+type Settings struct {
+	Input         InputSettings         `json:"input"`
+	Accessibility AccessibilitySettings `json:"accessibility"`
+	Display       DisplaySettings       `json:"display"`
+	Audio         AudioSettings         `json:"audio"`
+	Theme         string                `json:"theme"`
+	Language      string                `json:"language"`
+	Capture       CaptureSettings       `json:"capture"`
+}
+
+type AudioSettings struct {
+	Volumes map[string]float32 `json:"volumes"`
+	Muted   bool               `json:"muted"`
+}
+
+type DisplaySettings struct {
+	Width      int32     `json:"width"`
+	Height     int32     `json:"height"`
+	Fullscreen bool      `json:"fullscreen"`
+	ScaleMode  ScaleMode `json:"scale_mode"`
+}
+
+type AccessibilitySettings struct {
+	BallSymbols bool   `json:"ball_symbols"`
+	BallPalette string `json:"ball_palette"`
+}
+
+type InputSettings struct {
+	Bindings         map[InputAction][]string `json:"bindings"`
+	RotateSpeed      float32                  `json:"rotate_speed"`
+	RotateAccel      float32                  `json:"rotate_accel"`
+	RotateMaxSpeed   float32                  `json:"rotate_max_speed"`
+	GamepadDeadZone  float32                  `json:"gamepad_dead_zone"`
+	GamepadTriggerOn float32                  `json:"gamepad_trigger_threshold"`
+}
+
+var globalSettings Settings = NewSettings()
+
+func NewSettings() Settings {
+	return Settings{
+		Input: InputSettings{
+			Bindings: map[InputAction][]string{
+				InputAction_RotateLeft:  {"left", "a", "pad_left"},
+				InputAction_RotateRight: {"right", "d", "pad_right"},
+				InputAction_Fire:        {"mouse_left", "space", "up", "w", "pad_a", "pad_rt"},
+				InputAction_Swap:        {"mouse_right", "down", "s", "pad_b", "pad_lt"},
+				InputAction_Mute:        {"m"},
+				InputAction_Screenshot:  {"f12"},
+				InputAction_Record:      {"f9"},
+			},
+			RotateSpeed:      0.01,
+			RotateAccel:      0.004,
+			RotateMaxSpeed:   0.08,
+			GamepadDeadZone:  0.25,
+			GamepadTriggerOn: 0.5,
+		},
+		Accessibility: AccessibilitySettings{
+			BallSymbols: false,
+			BallPalette: "default",
+		},
+		Display: DisplaySettings{
+			Width:     GameWidth,
+			Height:    GameHeight,
+			ScaleMode: ScaleMode_Integer,
+		},
+		Audio: AudioSettings{
+			Volumes: map[string]float32{"master": 1, "music": 0.8, "sfx": 1, "ui": 1},
+		},
+		Language: DefaultLanguage,
+		Capture: CaptureSettings{
+			GifSeconds: 10,
+			GifFps:     20,
+			GifScale:   0.5,
+		},
+	}
+}
+
+// LoadSettings reads the settings file on top of the defaults, so missing entries keep their default value.
+// A missing file is not an error.
+func LoadSettings(filePath string) (Settings, error) {
+	settings := NewSettings()
+	raw, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return settings, nil
+	} else if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return NewSettings(), err
+	}
+	return settings, nil
+}
+
+func (settings *Settings) Save(filePath string) error {
+	raw, err := json.MarshalIndent(settings, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, raw, 0644)
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+type SimOptions struct {
+	NumGames  int32
+	Lives     int32
+	MaxFrames int32
+	Seed      int64
+	IsRandom  bool
+}
+
+type SimResult struct {
+	GraphicsId, SettingsId string
+	Won                    bool
+	FramesToTarget         int32
+	LivesLost              int32
+	Score                  int32
+	Frames                 int32
+	Stats                  GameStats
+}
+
+type SimSummary struct {
+	GraphicsId, SettingsId string
+	NumGames, NumWins      int32
+	NumReachedTarget       int32
+	NumInDanger            int32
+	TotalFramesToTarget    int64
+	TotalFrames            int64
+	TotalLivesLost         int32
+	TotalScore             int64
+	Stats                  GameStats
+}
+
+type ProgressionRow struct {
+	Stage               int32              `json:"stage"`
+	Level               int32              `json:"level"`
+	GraphicsId          string             `json:"graphics"`
+	SettingsId          string             `json:"settings"`
+	Games               int32              `json:"games"`
+	WinRate             float32            `json:"win_rate"`
+	TargetRate          float32            `json:"target_rate"`
+	MeanSecondsToTarget float32            `json:"mean_seconds_to_target"`
+	DangerRate          float32            `json:"danger_rate"`
+	MeanDangers         float32            `json:"mean_dangers"`
+	MeanLivesLost       float32            `json:"mean_lives_lost"`
+	MeanScore           float32            `json:"mean_score"`
+	NumColors           int32              `json:"colors"`
+	BallRepeat          int32              `json:"repeat"`
+	MaxSingle           int32              `json:"single"`
+	RepeatRate          float32            `json:"repeat_rate"`
+	SinglesPerGame      float32            `json:"singles_per_game"`
+	BallsSpawned        map[string]int32   `json:"balls_spawned"`
+	PowerUpFreq         map[string]int32   `json:"powerup_freq"`
+	PowerUpsPerMinute   map[string]float32 `json:"powerups_per_minute"`
+	powerUpsPerMinute   [PowerType_Max]float32
+	ballsSpawned        [len(globalBallColors)]int32
+}
+
+func InitHeadless() error {
+	globalHeadless = true
+	if err := InitGlobalTextures(); err != nil {
+		return err
+	}
+	return errors.Join(InitFonts(), InitLocalization())
+}
+
+func RunBotGame(theParser *LevelParser, theGraphicsId, theSettingsId string, theOptions *SimOptions) (SimResult, error) {
+	result := SimResult{GraphicsId: theGraphicsId, SettingsId: theSettingsId, FramesToTarget: -1}
+	board := NewBoard()
+	board.Lives = theOptions.Lives
+	globalBoard = board
+	defer func() { board.LevelScope.Release() }()
+	bot := NewBot(board)
+	bot.IsRandom = theOptions.IsRandom
+
+	start_level := func() error {
+		desc, found := theParser.MakeLevel(theGraphicsId, theSettingsId)
+		if !found {
+			return fmt.Errorf("unknown graphics %q or settings %q", theGraphicsId, theSettingsId)
+		}
+		return board.LoadLevel(desc)
+	}
+	if err := start_level(); err != nil {
+		return result, err
+	}
+
+	for result.Frames < theOptions.MaxFrames {
+		bot.Play()
+		board.Update()
+		result.Frames++
+
+		if board.HasReachedTarget && result.FramesToTarget < 0 {
+			result.FramesToTarget = result.Frames
+		}
+		if board.HasReachedTarget && board.IsBoardCleared() {
+			result.Won = true
+			break
+		}
+		if board.HasBallReachedHole() {
+			result.LivesLost++
+			board.Lives--
+			if board.Lives <= 0 {
+				break
+			}
+			result.Stats.Accumulate(&board.LevelStats)
+			board.Score, board.ScoreDisplay = board.LevelBeginScore, board.LevelBeginScore
+			if err := start_level(); err != nil {
+				return result, err
+			}
+		}
+	}
+	result.Stats.Accumulate(&board.LevelStats)
+	result.Score = board.Score
+	return result, nil
+}
+
+func RunSimGames(theParser *LevelParser, theGraphicsId, theSettingsId string, theOptions *SimOptions) (SimSummary, error) {
+	summary := SimSummary{GraphicsId: theGraphicsId, SettingsId: theSettingsId}
+	for i := range theOptions.NumGames {
+		SeedRandom(theOptions.Seed + int64(i))
+		result, err := RunBotGame(theParser, theGraphicsId, theSettingsId, theOptions)
+		if err != nil {
+			return summary, err
+		}
+		summary.Add(&result)
+	}
+	return summary, nil
+}
+
+func RunBalanceTest(theParser *LevelParser, theGraphicsId, theSettingsId string, theOptions *SimOptions, theOutput io.Writer) error {
+	graphics_ids := slices.Sorted(maps.Keys(theParser.GraphicsMap))
+	settings_ids := slices.Sorted(maps.Keys(theParser.SettingsMap))
+	if theGraphicsId != "" {
+		graphics_ids = []string{theGraphicsId}
+	}
+	if theSettingsId != "" {
+		settings_ids = []string{theSettingsId}
+	}
+
+	fmt.Fprintf(theOutput, "%-16s %-10s %6s %8s %12s %10s %10s\n", "graphics", "settings", "games", "win%", "target(s)", "livesLost", "score")
+	var total SimSummary
+	for _, graphics_id := range graphics_ids {
+		for _, settings_id := range settings_ids {
+			summary, err := RunSimGames(theParser, graphics_id, settings_id, theOptions)
+			if err != nil {
+				return err
+			}
+			summary.Print(theOutput)
+			total.Merge(&summary)
+		}
+	}
+	total.GraphicsId, total.SettingsId = "total", ""
+	total.Print(theOutput)
+	return nil
+}
+
+func RunProgressionReport(theParser *LevelParser, theOptions *SimOptions, theFormat string, theOutput io.Writer) error {
+	if theFormat != "csv" && theFormat != "json" {
+		return fmt.Errorf("unknown report format %q", theFormat)
+	}
+	var rows []ProgressionRow
+	for _, stage := range theParser.StageProgression {
+		for i, level := range stage.Levels {
+			desc, found := theParser.MakeLevel(level.GraphicsId, level.SettingsId)
+			if !found {
+				return fmt.Errorf("stage %d level %d: unknown graphics %q or settings %q", stage.Stage, i+1, level.GraphicsId, level.SettingsId)
+			}
+			summary, err := RunSimGames(theParser, level.GraphicsId, level.SettingsId, theOptions)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, NewProgressionRow(stage.Stage, int32(i+1), &summary, desc))
+		}
+	}
+
+	if theFormat == "json" {
+		encoder := json.NewEncoder(theOutput)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(rows)
+	}
+	return WriteProgressionCSV(rows, theOutput)
+}
+
+func NewProgressionRow(theStage, theLevel int32, theSummary *SimSummary, theDesc *LevelDesc) ProgressionRow {
+	games := float32(max(theSummary.NumGames, 1))
+	minutes := float32(max(theSummary.TotalFrames, 1)) / float32(TargetFPS*60)
+	stats := &theSummary.Stats
+	row := ProgressionRow{
+		Stage:               theStage,
+		Level:               theLevel,
+		GraphicsId:          theSummary.GraphicsId,
+		SettingsId:          theSummary.SettingsId,
+		Games:               theSummary.NumGames,
+		WinRate:             float32(theSummary.NumWins) / games,
+		TargetRate:          float32(theSummary.NumReachedTarget) / games,
+		MeanSecondsToTarget: theSummary.MeanSecondsToTarget(),
+		DangerRate:          float32(theSummary.NumInDanger) / games,
+		MeanDangers:         float32(stats.NumDangers) / games,
+		MeanLivesLost:       float32(theSummary.TotalLivesLost) / games,
+		MeanScore:           float32(theSummary.TotalScore) / games,
+		SinglesPerGame:      float32(stats.NumSingleSpawns) / games,
+		BallsSpawned:        make(map[string]int32),
+		PowerUpFreq:         make(map[string]int32),
+		PowerUpsPerMinute:   make(map[string]float32),
+	}
+
+	var total_spawned int32 = 0
+	for i := range stats.NumBallsSpawned {
+		total_spawned += stats.NumBallsSpawned[i]
+		row.ballsSpawned[i] = stats.NumBallsSpawned[i]
+		if stats.NumBallsSpawned[i] > 0 {
+			row.BallsSpawned[globalBallColorNames[i]] = stats.NumBallsSpawned[i]
+		}
+	}
+	if total_spawned > 0 {
+		row.RepeatRate = float32(stats.NumRepeatSpawns) / float32(total_spawned)
+	}
+
+	if len(theDesc.CurveDescs) != 0 {
+		curve_desc := &theDesc.CurveDescs[0]
+		row.NumColors, row.BallRepeat, row.MaxSingle = curve_desc.NumColors, curve_desc.BallRepeat, curve_desc.MaxSingle
+		for i := range PowerType_Max {
+			row.PowerUpFreq[globalPowerTypeNames[i]] = curve_desc.PowerUpFreq[i]
+		}
+	}
+	for i := range PowerType_Max {
+		row.powerUpsPerMinute[i] = float32(stats.NumPowerUpsSpawned[i]) / minutes
+		row.PowerUpsPerMinute[globalPowerTypeNames[i]] = row.powerUpsPerMinute[i]
+	}
+	return row
+}
+
+func WriteProgressionCSV(theRows []ProgressionRow, theOutput io.Writer) error {
+	header := []string{
+		"stage", "level", "graphics", "settings", "games", "win_rate", "target_rate", "mean_seconds_to_target",
+		"danger_rate", "mean_dangers", "mean_lives_lost", "mean_score", "colors", "repeat", "single", "repeat_rate", "singles_per_game",
+	}
+	for i := range globalBallColorNames {
+		header = append(header, "balls_"+globalBallColorNames[i])
+	}
+	for i := range PowerType_Max {
+		header = append(header, "freq_"+globalPowerTypeNames[i], "powerups_per_minute_"+globalPowerTypeNames[i])
+	}
+
+	writer := csv.NewWriter(theOutput)
+	writer.Write(header)
+	for _, row := range theRows {
+		fields := []string{
+			strconv.Itoa(int(row.Stage)), strconv.Itoa(int(row.Level)), row.GraphicsId, row.SettingsId,
+			strconv.Itoa(int(row.Games)), formatFloat(row.WinRate), formatFloat(row.TargetRate), formatFloat(row.MeanSecondsToTarget),
+			formatFloat(row.DangerRate), formatFloat(row.MeanDangers), formatFloat(row.MeanLivesLost), formatFloat(row.MeanScore),
+			strconv.Itoa(int(row.NumColors)), strconv.Itoa(int(row.BallRepeat)), strconv.Itoa(int(row.MaxSingle)),
+			formatFloat(row.RepeatRate), formatFloat(row.SinglesPerGame),
+		}
+		for i := range row.ballsSpawned {
+			fields = append(fields, strconv.Itoa(int(row.ballsSpawned[i])))
+		}
+		for i := range PowerType_Max {
+			fields = append(fields, strconv.Itoa(int(row.PowerUpFreq[globalPowerTypeNames[i]])), formatFloat(row.powerUpsPerMinute[i]))
+		}
+		writer.Write(fields)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatFloat(theValue float32) string {
+	return strconv.FormatFloat(float64(theValue), 'f', 3, 32)
+}
+
+func (summary *SimSummary) Add(theResult *SimResult) {
+	summary.NumGames++
+	if theResult.Won {
+		summary.NumWins++
+	}
+	if theResult.FramesToTarget >= 0 {
+		summary.NumReachedTarget++
+		summary.TotalFramesToTarget += int64(theResult.FramesToTarget)
+	}
+	if theResult.Stats.NumDangers > 0 {
+		summary.NumInDanger++
+	}
+	summary.TotalFrames += int64(theResult.Frames)
+	summary.TotalLivesLost += theResult.LivesLost
+	summary.TotalScore += int64(theResult.Score)
+	summary.Stats.Accumulate(&theResult.Stats)
+}
+
+func (summary *SimSummary) Merge(theOther *SimSummary) {
+	summary.NumGames += theOther.NumGames
+	summary.NumWins += theOther.NumWins
+	summary.NumReachedTarget += theOther.NumReachedTarget
+	summary.NumInDanger += theOther.NumInDanger
+	summary.TotalFramesToTarget += theOther.TotalFramesToTarget
+	summary.TotalFrames += theOther.TotalFrames
+	summary.TotalLivesLost += theOther.TotalLivesLost
+	summary.TotalScore += theOther.TotalScore
+	summary.Stats.Accumulate(&theOther.Stats)
+}
+
+func (summary *SimSummary) MeanSecondsToTarget() float32 {
+	if summary.NumReachedTarget == 0 {
+		return -1
+	}
+	return float32(summary.TotalFramesToTarget) / float32(summary.NumReachedTarget) / float32(TargetFPS)
+}
+
+func (summary *SimSummary) Print(theOutput io.Writer) {
+	games := max(summary.NumGames, 1)
+	fmt.Fprintf(theOutput, "%-16s %-10s %6d %7.1f%% %12.1f %10.2f %10d\n",
+		summary.GraphicsId, summary.SettingsId, summary.NumGames,
+		100*float32(summary.NumWins)/float32(games),
+		summary.MeanSecondsToTarget(),
+		float32(summary.TotalLivesLost)/float32(games),
+		summary.TotalScore/int64(games),
+	)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/binary"
+	"log/slog"
 	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
@@ -8,67 +10,93 @@ import (
 
 type SoundMgr struct {
 	UpdateCount   int32
-	SoundMap      map[int32]SoundDesc
+	DelayedSounds []DelayedSound
 	LoopingSounds [LoopType_Max]*LoopingSound
 }
 
+// DelayedSound plays once the update count reaches Frame.
+type DelayedSound struct {
+	Frame int32
+	Desc  SoundDesc
+}
+
 type SoundDesc struct {
-	rl.Sound
-	Pan, Pitch float32
+	Key                SoundKey
+	Volume, Pan, Pitch float32
 }
 
 func InitSoundManager() *SoundMgr {
-	mgr := &SoundMgr{UpdateCount: 0, SoundMap: make(map[int32]SoundDesc)}
+	mgr := &SoundMgr{UpdateCount: 0}
 	for i := range LoopType_Max {
-		mgr.LoopingSounds[i] = &LoopingSound{Sound: new(SoundDesc), Volume: 0}
+		mgr.LoopingSounds[i] = &LoopingSound{}
+	}
+	if globalHeadless {
+		return mgr
+	}
+	for i := range LoopType_Max {
+		if loop, found := gLoopFiles[i]; found {
+			if sound, err := LoadAssetSound(loop.FilePath); err == nil {
+				mgr.LoopingSounds[i].Load(sound, loop.Volume)
+			} else {
+				slog.Warn("cannot load a looping sound", "err", err)
+			}
+		}
+	}
+	if !mgr.LoopingSounds[LoopType_Danger].IsLoaded {
+		mgr.LoopingSounds[LoopType_Danger].Load(MakeHeartbeatSound(), 1)
 	}
-	mgr.LoopingSounds[LoopType_RollIn].Sound.Sound = rl.LoadSound("sounds/rolling.ogg")
-	mgr.LoopingSounds[LoopType_RollOut].Sound.Sound = rl.LoadSound("sounds/rolling.ogg")
 	return mgr
 }
 
 func (mgr *SoundMgr) Destroy() {
-	rl.UnloadSound(mgr.LoopingSounds[LoopType_RollIn].Sound.Sound)
-	rl.UnloadSound(mgr.LoopingSounds[LoopType_RollOut].Sound.Sound)
+	for i := range LoopType_Max {
+		mgr.LoopingSounds[i].Unload()
+	}
 }
 
-func (mgr *SoundMgr) AddSound(theSound rl.Sound, theDelay, thePan int32, thePitchShift float32) {
-	tmp := SoundDesc{theSound, float32(thePan), thePitchShift}
+// AddSound plays a sound after theDelay frames. thePan goes from -1 (left) to 1 (right), see GetPanForX.
+func (mgr *SoundMgr) AddSound(theSound SoundKey, theDelay int32, thePan, thePitchShift float32) {
+	mgr.AddSoundDesc(SoundDesc{theSound, 1, thePan, thePitchShift}, theDelay)
+}
+
+func (mgr *SoundMgr) AddSoundDesc(theDesc SoundDesc, theDelay int32) {
 	if theDelay == 0 {
-		mgr.PlaySample(tmp)
+		mgr.PlaySample(theDesc)
 	} else {
-		mgr.SoundMap[mgr.UpdateCount+theDelay] = tmp
+		mgr.DelayedSounds = append(mgr.DelayedSounds, DelayedSound{mgr.UpdateCount + theDelay, theDesc})
 	}
 }
 
 func (mgr SoundMgr) PlaySample(theDesc SoundDesc) {
-	if theDesc.Pan != 0 {
-		rl.SetSoundPan(theDesc.Sound, theDesc.Pan)
-	}
-	if theDesc.Pitch != 0 {
-		rl.SetSoundPitch(theDesc.Sound, float32(math.Pow(1.0594630943592952645618252949463, float64(theDesc.Pitch))))
-	}
-	rl.PlaySound(theDesc.Sound)
+	PlayGameSound(theDesc.Key, theDesc.Volume, theDesc.Pan, theDesc.Pitch)
 }
 
-func (mgr SoundMgr) PlayLoop(theSound LoopType) {
-	mgr.LoopingSounds[theSound].Play()
+// PlayLoop starts a loop, fading it in over theFadeFrames. Playing a loop that already plays only
+// cancels a running fade out.
+func (mgr SoundMgr) PlayLoop(theSound LoopType, theFadeFrames int32) {
+	mgr.LoopingSounds[theSound].Play(theFadeFrames)
 }
 
-func (mgr SoundMgr) StopLoop(theSound LoopType) {
-	if mgr.LoopingSounds[theSound].Volume > 0.99 {
-		mgr.LoopingSounds[theSound].Volume = 0.98
-	}
+func (mgr SoundMgr) SetLoopVolume(theSound LoopType, theVolume float32) {
+	mgr.LoopingSounds[theSound].TargetVolume = theVolume
+}
+
+func (mgr SoundMgr) StopLoop(theSound LoopType, theFadeFrames int32) {
+	mgr.LoopingSounds[theSound].Stop(theFadeFrames)
 }
 
 func (mgr *SoundMgr) Update() {
 	mgr.UpdateCount++
-	for i := range mgr.SoundMap {
-		if i <= mgr.UpdateCount {
-			mgr.PlaySample(mgr.SoundMap[i])
-			delete(mgr.SoundMap, i)
+	num_waiting := 0
+	for _, delayed := range mgr.DelayedSounds {
+		if delayed.Frame <= mgr.UpdateCount {
+			mgr.PlaySample(delayed.Desc)
+		} else {
+			mgr.DelayedSounds[num_waiting] = delayed
+			num_waiting++
 		}
 	}
+	mgr.DelayedSounds = mgr.DelayedSounds[:num_waiting]
 	for i := range LoopType_Max {
 		mgr.LoopingSounds[i].Update()
 	}
@@ -79,44 +107,96 @@ type LoopType int32
 const (
 	LoopType_RollIn LoopType = iota
 	LoopType_RollOut
+	LoopType_Danger
 	LoopType_Max
 )
 
+// LoopingSound restarts its sound from SoundMgr.Update whenever it ends, so everything happens on the main thread.
 type LoopingSound struct {
-	Sound     *SoundDesc
-	Volume    float32
-	IsPlaying bool
+	Sound        rl.Sound
+	BaseVolume   float32
+	IsLoaded     bool
+	IsPlaying    bool
+	Volume       float32
+	TargetVolume float32
+	FadeStep     float32
 }
 
-func (loops *LoopingSound) Play() {
-	if loops.Sound == nil {
+func (loops *LoopingSound) Load(theSound rl.Sound, theVolume float32) {
+	loops.Sound, loops.BaseVolume, loops.IsLoaded = theSound, theVolume, true
+}
+
+func (loops *LoopingSound) Play(theFadeFrames int32) {
+	loops.TargetVolume = 1
+	loops.FadeStep = 1 / float32(max(theFadeFrames, 1))
+	if !loops.IsPlaying {
+		loops.IsPlaying = true
+		loops.Volume = 0
+		if theFadeFrames <= 0 {
+			loops.Volume = 1
+		}
+	}
+}
+
+func (loops *LoopingSound) Stop(theFadeFrames int32) {
+	if !loops.IsPlaying {
 		return
 	}
-	loops.IsPlaying = true
-	loops.Volume = 1
-	rl.SetSoundVolume(loops.Sound.Sound, 1)
-	go func() {
-		for {
-			if !loops.IsPlaying {
-				return
-			}
-			if !rl.IsSoundPlaying(loops.Sound.Sound) {
-				rl.PlaySound(loops.Sound.Sound)
-			}
-		}
-	}()
+	loops.TargetVolume = 0
+	loops.FadeStep = 1 / float32(max(theFadeFrames, 1))
+}
+
+func (loops *LoopingSound) Unload() {
+	if loops.IsLoaded {
+		rl.StopSound(loops.Sound)
+		rl.UnloadSound(loops.Sound)
+	}
+	loops.IsLoaded, loops.IsPlaying = false, false
 }
 
 func (loops *LoopingSound) Update() {
-	if loops.Sound == nil || !loops.IsPlaying {
+	if !loops.IsPlaying {
 		return
 	}
-	if loops.Volume < 1 {
-		loops.Volume -= 0.02
-		if loops.Volume > 0 {
-			rl.SetSoundVolume(loops.Sound.Sound, loops.Volume)
-		} else {
-			loops.IsPlaying = false
+	if loops.Volume < loops.TargetVolume {
+		loops.Volume = min(loops.Volume+loops.FadeStep, loops.TargetVolume)
+	} else if loops.Volume > loops.TargetVolume {
+		loops.Volume = max(loops.Volume-loops.FadeStep, loops.TargetVolume)
+	}
+	if loops.Volume <= 0 && loops.TargetVolume <= 0 {
+		loops.IsPlaying = false
+		if loops.IsLoaded {
+			rl.StopSound(loops.Sound)
+		}
+		return
+	}
+	if !loops.IsLoaded {
+		return
+	}
+	rl.SetSoundVolume(loops.Sound, loops.Volume*loops.BaseVolume*GetBusVolume(AudioBus_Sfx))
+	if !rl.IsSoundPlaying(loops.Sound) {
+		rl.PlaySound(loops.Sound)
+	}
+}
+
+// MakeHeartbeatSound synthesizes the two low thumps of a heart beat followed by a pause,
+// so the danger loop does not need an extra sound file.
+func MakeHeartbeatSound() rl.Sound {
+	const sample_rate = 22050
+	samples := make([]int16, sample_rate*9/10)
+	for _, beat := range []struct {
+		Start, Freq, Gain float64
+	}{{0, 55, 1}, {0.22, 48, 0.7}} {
+		start := int(beat.Start * sample_rate)
+		for i := range sample_rate / 6 {
+			t := float64(i) / sample_rate
+			value := beat.Gain * math.Exp(-t*28) * math.Sin(2*math.Pi*beat.Freq*t)
+			samples[start+i] += int16(value * 20000)
 		}
 	}
+	data := make([]byte, len(samples)*2)
+	for i, sample := range samples {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(sample))
+	}
+	return rl.LoadSoundFromWave(rl.NewWave(uint32(len(samples)), sample_rate, 16, 1, data))
 }
@@ -0,0 +1,66 @@
+package main
+
+const GridCellSize int32 = DefaultBallRadius * 2
+const GridCols int32 = (GameWidth + GridCellSize - 1) / GridCellSize
+const GridRows int32 = (GameHeight + GridCellSize - 1) / GridCellSize
+
+// SpatialGrid buckets items by position into cells one ball wide over the play field.
+// Items off the field go into the border cells, so Query still finds everything closer than a cell size.
+type SpatialGrid[T any] struct {
+	Cells [GridCols * GridRows][]T
+}
+
+func GetGridCell(x, y float32) (int32, int32) {
+	col := int32(min(max(x, 0), float32(GameWidth-1))) / GridCellSize
+	row := int32(min(max(y, 0), float32(GameHeight-1))) / GridCellSize
+	return col, row
+}
+
+// Clear empties the cells but keeps their memory.
+func (grid *SpatialGrid[T]) Clear() {
+	for i := range grid.Cells {
+		grid.Cells[i] = grid.Cells[i][:0]
+	}
+}
+
+func (grid *SpatialGrid[T]) Add(x, y float32, theItem T) {
+	col, row := GetGridCell(x, y)
+	index := row*GridCols + col
+	grid.Cells[index] = append(grid.Cells[index], theItem)
+}
+
+// Query appends the items of the cell of x, y and of the cells around it to theItems.
+func (grid *SpatialGrid[T]) Query(x, y float32, theItems []T) []T {
+	col, row := GetGridCell(x, y)
+	for r := max(row-1, 0); r <= min(row+1, GridRows-1); r++ {
+		for c := max(col-1, 0); c <= min(col+1, GridCols-1); c++ {
+			theItems = append(theItems, grid.Cells[r*GridCols+c]...)
+		}
+	}
+	return theItems
+}
+
+// BuildWayPointGrid indexes the waypoints a gap shot can be detected at, every ball diameter outside of tunnels.
+func (curve *Curve) BuildWayPointGrid() {
+	curve.WayPointGrid.Clear()
+	way_points := curve.WayPointMgr.WayPoints
+	for i := int32(1); i < int32(len(way_points)); i += DefaultBallRadius * 2 {
+		if !way_points[i].InTunnel {
+			curve.WayPointGrid.Add(way_points[i].X, way_points[i].Y, i)
+		}
+	}
+	curve.BallGridDirty = true
+}
+
+// UpdateBallGrid refills the ball grid once per frame, or again when the chain changed since.
+func (curve *Curve) UpdateBallGrid() {
+	if curve.BallGridFrame == curve.Board.StateCount && !curve.BallGridDirty {
+		return
+	}
+	curve.BallGrid.Clear()
+	for _, ball := range curve.BallList {
+		curve.BallGrid.Add(ball.X, ball.Y, ball)
+	}
+	curve.BallGridFrame = curve.Board.StateCount
+	curve.BallGridDirty = false
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"image"
 	"image/color"
-	"image/png"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
 	"math"
-	"os"
+	"path"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -15,6 +19,7 @@ type SpriteMgr struct {
 	UpdateCnt            int32
 	InSpace, SpaceScroll bool
 	Sprites              [MaxPriority][]SpriteImage
+	BackgroundAlphas     []SpriteImage // pieces of the background for the level intro
 	HoleMappings         []int32
 	HoleInfos            []HoleInfo
 	HoleFlashes          []HoleFlash
@@ -68,14 +73,15 @@ func (mgr *SpriteMgr) DrawBackground() {
 }
 
 func (mgr *SpriteMgr) DrawHole(theHoleIndex int, tint color.RGBA) {
-	size := gTextures[Texture_HoleCover].Width
 	hole_texture := gTextures[Texture_Hole]
 	hole_cover := gTextures[Texture_HoleCover]
 	hole_info := &mgr.HoleInfos[theHoleIndex]
+	cover_cell := GetSprite(Texture_HoleCover).GetCell(hole_info.Frame)
+	size := int32(cover_cell.Width)
 	rl.DrawTexturePro(hole_texture, rect(0, 0, hole_texture.Width, hole_texture.Height),
 		rect(hole_info.X, hole_info.Y, hole_texture.Width, hole_texture.Height), vec2(hole_texture.Width/2, hole_texture.Height/2),
 		-hole_info.Rotation*rl.Rad2deg, tint)
-	rl.DrawTexturePro(hole_cover, rect(0, hole_info.Frame*size, size, size),
+	rl.DrawTexturePro(hole_cover, cover_cell,
 		rect(hole_info.X, hole_info.Y, size, size), vec2(size/2, size/2),
 		-hole_info.Rotation*rl.Rad2deg, tint)
 }
@@ -90,35 +96,114 @@ func (mgr *SpriteMgr) DrawSprites2(theList []SpriteImage) {
 	}
 }
 
-func (mgr *SpriteMgr) SetupLevel(theLevel *LevelDesc) {
-	for i := range theLevel.Sprites {
-		desc := theLevel.Sprites[i]
-		down_cast := rl.LoadImageFromTexture(mgr.BackgroundImage)
-		background := down_cast.ToImage().(*image.RGBA)
-
-		f, _ := os.Open(desc.ImagePath)
-		defer f.Close()
-		tmp, _ := png.Decode(f)
-
-		the_mask := tmp.(*image.RGBA)
-		final := image.NewRGBA(image.Rect(0, 0, the_mask.Rect.Dx(), the_mask.Rect.Dy()))
-		xint, yint := int(desc.X), int(desc.Y)
-		for y := range the_mask.Rect.Dy() {
-			for x := range the_mask.Rect.Dx() {
-				pixel := background.RGBAAt(x+xint, y+yint)
-				final.SetRGBA(x, y, color.RGBA{pixel.R, pixel.G, pixel.B, the_mask.RGBAAt(x, y).R})
+// SetupLevel loads the background and cuts the level sprites and the alpha layers out of it through theScope.
+// A cutout takes its alpha from the red channel of its mask, an alpha layer from its gray gif.
+func (mgr *SpriteMgr) SetupLevel(theLevel *LevelDesc, theScope *LevelScope, theBackgroundPath string) error {
+	if theBackgroundPath == "" {
+		return nil
+	}
+	var errs []error
+	texture, err := theScope.AcquireTexture(theBackgroundPath)
+	mgr.BackgroundImage = texture
+	if err != nil || globalHeadless {
+		return err
+	}
+
+	// The background is decoded at most once, and only if a cutout is not loaded yet.
+	var background image.Image = nil
+	cut := func(theMaskPath string, theX, theY int32) (rl.Texture2D, error) {
+		sources := []string{theBackgroundPath, theMaskPath}
+		// the offset is part of the key, the same mask at another place cuts out another part of the background
+		key := fmt.Sprintf("cutout:%s:%s:%d,%d", theBackgroundPath, theMaskPath, theX, theY)
+		return theScope.AcquireGeneratedTexture(key, sources, func() (rl.Texture2D, error) {
+			if background == nil {
+				var err error
+				if background, err = decodeImageFile(theBackgroundPath); err != nil {
+					return rl.Texture2D{}, err
+				}
 			}
-		}
+			mask, err := decodeImageFile(theMaskPath)
+			if err != nil {
+				return rl.Texture2D{}, err
+			}
+			return makeCutoutTexture(background, mask, theX, theY), nil
+		})
+	}
 
-		re_texture := rl.LoadTextureFromImage(rl.NewImageFromImage(final))
-		priority := desc.Priority
-		if priority >= MaxPriority {
-			priority = MaxPriority - 1
+	for i := range theLevel.Sprites {
+		desc := &theLevel.Sprites[i]
+		texture, err := cut(desc.ImagePath, desc.X, desc.Y)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
+		priority := min(desc.Priority, MaxPriority-1)
 		mgr.Sprites[priority] = append(mgr.Sprites[priority], SpriteImage{
-			desc.X, desc.Y, 0, 0, re_texture,
+			desc.X, desc.Y, 0, 0, texture,
 		})
 	}
+
+	for i := range theLevel.BackgroundAlphas {
+		desc := &theLevel.BackgroundAlphas[i]
+		texture, err := cut(getAlphaPath(theBackgroundPath, desc), desc.X, desc.Y)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		mgr.BackgroundAlphas = append(mgr.BackgroundAlphas, SpriteImage{desc.X, desc.Y, desc.VX, desc.VY, texture})
+	}
+	return errors.Join(errs...)
+}
+
+// getAlphaPath finds the gray gif of an alpha layer next to the background.
+func getAlphaPath(theBackgroundPath string, theDesc *SpriteDesc) string {
+	return path.Join(path.Dir(theBackgroundPath), "_"+theDesc.ImagePath+".gif")
+}
+
+// GetMaskPaths lists the masks the sprites and alpha layers of a level are cut with.
+func GetMaskPaths(theLevel *LevelDesc) []string {
+	background_path := GetBackgroundPath(theLevel)
+	if background_path == "" {
+		return nil
+	}
+	var paths []string
+	for i := range theLevel.Sprites {
+		paths = append(paths, theLevel.Sprites[i].ImagePath)
+	}
+	for i := range theLevel.BackgroundAlphas {
+		paths = append(paths, getAlphaPath(background_path, &theLevel.BackgroundAlphas[i]))
+	}
+	return paths
+}
+
+func makeCutoutTexture(theBackground, theMask image.Image, theX, theY int32) rl.Texture2D {
+	bounds := theMask.Bounds()
+	final := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := range bounds.Dy() {
+		for x := range bounds.Dx() {
+			var r, g, b uint32 = 0, 0, 0
+			if point := image.Pt(x+int(theX), y+int(theY)); point.In(theBackground.Bounds()) {
+				r, g, b, _ = theBackground.At(point.X, point.Y).RGBA()
+			}
+			alpha := color.NRGBAModel.Convert(theMask.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA).R
+			final.SetNRGBA(x, y, color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), alpha})
+		}
+	}
+	return rl.LoadTextureFromImage(rl.NewImage(final.Pix, int32(bounds.Dx()), int32(bounds.Dy()), 1, rl.UncompressedR8g8b8a8))
+}
+
+// decodeImageFile reads a png or jpeg file in whatever color model it was saved in.
+func decodeImageFile(theFilePath string) (image.Image, error) {
+	file, err := globalVFS.Open(theFilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", theFilePath, err)
+	}
+	return img, nil
 }
 
 func (mgr *SpriteMgr) PlaceHole(theCurveIndex, theX, theY int32, theRotation float32) {
@@ -188,7 +273,7 @@ func (mgr *SpriteMgr) UpdateHole(theCurveIndex int32, thePercentOpen float32) {
 		the_max = max(hole.PercentOpen[i], the_max)
 	}
 
-	num_rows := gTextures[Texture_HoleCover].Height / gTextures[Texture_HoleCover].Width
+	num_rows := gTextureLayouts[Texture_HoleCover].Rows
 	hole.Frame = int32(float32(num_rows) * the_max)
 	if hole.Frame >= num_rows {
 		hole.Frame = num_rows - 1
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+	"github.com/tidwall/gjson"
+)
+
+const ThemesDir string = "./themes"
+
+// Theme overrides any subset of the default art. Whatever a theme does not provide keeps the default.
+type Theme struct {
+	Name           string
+	Textures       map[TextureKey]rl.Texture2D
+	TextureLayouts map[TextureKey]SpriteLayout
+	TexturePaths   map[TextureKey]string
+	Fonts          map[FontType]BitmapFont
+	FontImages     []string
+	Palette        BallPalette
+}
+
+var globalTheme *Theme = nil
+var globalDefaultBallPalette BallPalette = globalBallPalettes["default"]
+
+func GetThemeNames() []string {
+	entries, _ := globalVFS.ReadDir(ThemesDir)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if _, err := globalVFS.Stat(path.Join(ThemesDir, entry.Name(), "theme.json")); entry.IsDir() && err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+func GetThemeName() string {
+	if globalTheme == nil {
+		return ""
+	}
+	return globalTheme.Name
+}
+
+// LoadTheme reads themes/<name>/theme.json. Files that fail to load are reported, the rest of the theme is still used.
+func LoadTheme(theName string) (*Theme, error) {
+	dir := path.Join(ThemesDir, theName)
+	source := path.Join(dir, "theme.json")
+	raw, err := globalVFS.ReadFile(source)
+	if err != nil {
+		return nil, err
+	}
+	json := string(raw)
+	if !gjson.Valid(json) {
+		return nil, fmt.Errorf("%s: invalid json", source)
+	}
+
+	theme := &Theme{
+		Name:           theName,
+		Textures:       make(map[TextureKey]rl.Texture2D),
+		TextureLayouts: make(map[TextureKey]SpriteLayout),
+		TexturePaths:   make(map[TextureKey]string),
+		Fonts:          make(map[FontType]BitmapFont),
+		Palette:        globalDefaultBallPalette,
+	}
+	errs := []error{LoadTextureManifest(gjson.Get(json, "textures"), source, dir, theme.Textures, theme.TextureLayouts, theme.TexturePaths)}
+
+	for name, value := range gjson.Get(json, "fonts").Map() {
+		font_type, found := globalFontNames[name]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s: unknown font %q", source, name))
+			continue
+		}
+		font, err := LoadBitmapFont(path.Join(dir, value.String()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: font %q: %w", source, name, err))
+			continue
+		}
+		for _, image_path := range font.GetImagePaths() {
+			if !slices.Contains(theme.FontImages, image_path) && !slices.Contains(getDefaultFontImages(), image_path) {
+				theme.FontImages = append(theme.FontImages, image_path)
+			}
+		}
+		theme.Fonts[font_type] = font
+	}
+
+	palette := gjson.Get(json, "palette")
+	for key, colors := range map[string]*[6]color.RGBA{
+		"colors": &theme.Palette.Colors, "bright": &theme.Palette.BrightColors, "text": &theme.Palette.TextColors,
+	} {
+		if err := parsePaletteColors(palette.Get(key), colors); err != nil {
+			errs = append(errs, fmt.Errorf("%s: palette %s: %w", source, key, err))
+		}
+	}
+	return theme, errors.Join(errs...)
+}
+
+// parsePaletteColors reads a list of [r, g, b] or [r, g, b, a] colors, null entries keep the default color.
+func parsePaletteColors(theList gjson.Result, theColors *[6]color.RGBA) error {
+	if !theList.Exists() {
+		return nil
+	}
+	entries := theList.Array()
+	if len(entries) > len(theColors) {
+		return fmt.Errorf("expected at most %d colors", len(theColors))
+	}
+	for i, entry := range entries {
+		if entry.Type == gjson.Null {
+			continue
+		}
+		channels := entry.Array()
+		if len(channels) < 3 || len(channels) > 4 {
+			return fmt.Errorf("color %d: expected 3 or 4 channels", i)
+		}
+		theColors[i] = color.RGBA{uint8(channels[0].Int()), uint8(channels[1].Int()), uint8(channels[2].Int()), 255}
+		if len(channels) == 4 {
+			theColors[i].A = uint8(channels[3].Int())
+		}
+	}
+	return nil
+}
+
+// ApplyTheme switches to a theme, an empty name goes back to the default art.
+// The accessibility palette is applied on top, so it keeps working with any theme.
+func ApplyTheme(theName string) error {
+	if theName == GetThemeName() {
+		return nil
+	}
+	var theme *Theme = nil
+	var err error = nil
+	if theName != "" {
+		if theme, err = LoadTheme(theName); theme == nil {
+			return err
+		}
+	}
+
+	UnloadTheme()
+	globalTheme = theme
+	clear(gTextures)
+	clear(gTextureLayouts)
+	maps.Copy(gTextures, gDefaultTextures)
+	maps.Copy(gTextureLayouts, gDefaultTextureLayouts)
+	globalBallPalettes["default"] = globalDefaultBallPalette
+	if theme != nil {
+		maps.Copy(gTextures, theme.Textures)
+		maps.Copy(gTextureLayouts, theme.TextureLayouts)
+		globalBallPalettes["default"] = theme.Palette
+	}
+	BuildAtlases()
+	RefreshFonts()
+	return errors.Join(err, ApplyBallPalette(globalSettings.Accessibility.BallPalette))
+}
+
+// SelectLevelTheme applies the theme of a level, or the theme picked in the settings if the level has none.
+func SelectLevelTheme(theDesc *LevelDesc) {
+	name := theDesc.Theme
+	if name == "" {
+		name = globalSettings.Theme
+	}
+	if err := ApplyTheme(name); err != nil {
+		LogErrors(slog.LevelWarn, "theme incomplete", err, "theme", name)
+	}
+}
+
+func UnloadTheme() {
+	if globalTheme == nil {
+		return
+	}
+	for key, texture := range globalTheme.Textures {
+		UnloadGameTexture(texture)
+		gTextures[key] = gDefaultTextures[key]
+		gTextureLayouts[key] = gDefaultTextureLayouts[key]
+	}
+	for _, image_path := range globalTheme.FontImages {
+		UnloadGameTexture(gFontTextures[image_path])
+		delete(gFontTextures, image_path)
+	}
+	globalBallPalettes["default"] = globalDefaultBallPalette
+	globalTheme = nil
+	RefreshFonts()
+}
+
+// RefreshFonts stacks the fonts of the current language over the theme fonts over the default fonts.
+func RefreshFonts() {
+	clear(gFonts)
+	maps.Copy(gFonts, gDefaultFonts)
+	if globalTheme != nil {
+		maps.Copy(gFonts, globalTheme.Fonts)
+	}
+	if globalCatalog != nil {
+		maps.Copy(gFonts, globalCatalog.Fonts)
+	}
+}
+
+// getDefaultFontImages lists the textures of the default fonts, which a theme must not unload.
+func getDefaultFontImages() []string {
+	var paths []string
+	for _, font := range gDefaultFonts {
+		paths = append(paths, font.GetImagePaths()...)
+	}
+	return paths
+}
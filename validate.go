@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// ValidateAssets loads every asset headless and returns all problems found: the manifests, every level
+// with its curves and masks, the themes and the languages. Music is optional, missing tracks are only warned about,
+// as are strings a language lacks.
+func ValidateAssets() error {
+	globalHeadless = true
+	errs := []error{InitGlobalTextures(), InitGlobalSounds(), InitFonts(), InitLocalization()}
+
+	parser := NewLevelParser()
+	errs = append(errs, parser.ParseLevels(LevelsPath))
+	board := NewBoard()
+	globalBoard = board
+	for _, id := range slices.Sorted(maps.Keys(parser.GraphicsMap)) {
+		desc, _ := parser.MakeLevel(id, "")
+		errs = append(errs, board.SetupLevel(desc))
+		for _, mask_path := range GetMaskPaths(desc) {
+			if _, err := globalVFS.Stat(mask_path); err != nil {
+				errs = append(errs, fmt.Errorf("level %q: %w", id, err))
+			}
+		}
+	}
+	board.LevelScope.Release()
+
+	config, err := LoadMusicConfig(MusicConfigPath)
+	errs = append(errs, err)
+	tracks := map[string]bool{config.Menu: true, config.Victory: true, config.Level.Track: true, config.Level.Tension: true}
+	for _, entry := range append(slices.Collect(maps.Values(config.Stages)), slices.Collect(maps.Values(config.Graphics))...) {
+		tracks[entry.Track], tracks[entry.Tension] = true, true
+	}
+	delete(tracks, "")
+	for _, track := range slices.Sorted(maps.Keys(tracks)) {
+		if _, err := globalVFS.Stat(track); err != nil {
+			slog.Warn("music track missing", "file", MusicConfigPath, "err", err)
+		}
+	}
+
+	for _, name := range GetThemeNames() {
+		_, err := LoadTheme(name)
+		errs = append(errs, err)
+	}
+	for _, language := range GetLanguageNames() {
+		catalog, err := LoadCatalog(language)
+		errs = append(errs, err)
+		if catalog == nil || globalDefaultCatalog == nil {
+			continue
+		}
+		for _, key := range slices.Sorted(maps.Keys(globalDefaultCatalog.Strings)) {
+			if _, found := catalog.Strings[key]; !found {
+				slog.Warn("string missing, the default language is used", "language", language, "key", key)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"archive/zip"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	rl "github.com/gen2brain/raylib-go/raylib"
+)
+
+// The default assets are built into the binary, so the game runs from any directory.
+//
+//go:embed all:images all:sounds all:levels all:fonts all:lang all:themes
+var embeddedAssets embed.FS
+
+// VFS is the file system every asset is read from. Paths are slash separated and relative to the asset root.
+type VFS interface {
+	fs.ReadFileFS
+	fs.ReadDirFS
+	fs.StatFS
+}
+
+// OverlayFS looks files up in its layers from the top down, the first layer having a file wins.
+// Directory listings are merged. Names are matched ignoring case when there is no exact match,
+// the original level files were written for a file system that ignores case.
+type OverlayFS struct {
+	Layers  []fs.FS
+	Closers []io.Closer
+}
+
+var _ VFS = (*OverlayFS)(nil)
+
+var globalVFS *OverlayFS = NewOverlayFS(embeddedAssets)
+
+func NewOverlayFS(theBase fs.FS) *OverlayFS {
+	return &OverlayFS{Layers: []fs.FS{theBase}}
+}
+
+// Mount puts a data directory or a zip mod pack on top of the layers.
+func (vfs *OverlayFS) Mount(theDataPath string) error {
+	info, err := os.Stat(theDataPath)
+	if err != nil {
+		return err
+	}
+	var layer fs.FS
+	if info.IsDir() {
+		layer = os.DirFS(theDataPath)
+	} else {
+		reader, err := zip.OpenReader(theDataPath)
+		if err != nil {
+			return fmt.Errorf("%s: %w", theDataPath, err)
+		}
+		vfs.Closers = append(vfs.Closers, reader)
+		layer = reader
+	}
+	vfs.Layers = slices.Insert(vfs.Layers, 0, layer)
+	return nil
+}
+
+func (vfs *OverlayFS) Close() error {
+	var errs []error
+	for _, closer := range vfs.Closers {
+		errs = append(errs, closer.Close())
+	}
+	vfs.Closers = nil
+	return errors.Join(errs...)
+}
+
+// CleanAssetPath turns the paths used by the manifests, like "./images/a.png", into file system paths.
+func CleanAssetPath(theName string) string {
+	return path.Clean(strings.TrimPrefix(strings.ReplaceAll(theName, "\\", "/"), "/"))
+}
+
+// SameAssetPath tells whether two paths name the same asset, the way the VFS looks them up.
+func SameAssetPath(a, b string) bool {
+	return strings.EqualFold(CleanAssetPath(a), CleanAssetPath(b))
+}
+
+// resolve finds theName in a layer, first as it is written, then ignoring the case of every element.
+func resolve(theLayer fs.FS, theName string) (string, bool) {
+	if _, err := fs.Stat(theLayer, theName); err == nil {
+		return theName, true
+	}
+	if theName == "." {
+		return "", false
+	}
+	dir, found := resolve(theLayer, path.Dir(theName))
+	if !found {
+		return "", false
+	}
+	entries, err := fs.ReadDir(theLayer, dir)
+	if err != nil {
+		return "", false
+	}
+	base := path.Base(theName)
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name(), base) {
+			return path.Join(dir, entry.Name()), true
+		}
+	}
+	return "", false
+}
+
+func (vfs *OverlayFS) find(theOp, theName string) (fs.FS, string, error) {
+	name := CleanAssetPath(theName)
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: theOp, Path: theName, Err: fs.ErrInvalid}
+	}
+	for _, layer := range vfs.Layers {
+		if found_name, found := resolve(layer, name); found {
+			return layer, found_name, nil
+		}
+	}
+	return nil, "", &fs.PathError{Op: theOp, Path: theName, Err: fs.ErrNotExist}
+}
+
+func (vfs *OverlayFS) Open(theName string) (fs.File, error) {
+	layer, name, err := vfs.find("open", theName)
+	if err != nil {
+		return nil, err
+	}
+	return layer.Open(name)
+}
+
+func (vfs *OverlayFS) ReadFile(theName string) ([]byte, error) {
+	layer, name, err := vfs.find("read", theName)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(layer, name)
+}
+
+func (vfs *OverlayFS) Stat(theName string) (fs.FileInfo, error) {
+	layer, name, err := vfs.find("stat", theName)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(layer, name)
+}
+
+// ReadDir merges the directory of theName over all layers, sorted by name.
+func (vfs *OverlayFS) ReadDir(theName string) ([]fs.DirEntry, error) {
+	merged := make(map[string]fs.DirEntry)
+	found_dir := false
+	for i := len(vfs.Layers) - 1; i >= 0; i-- {
+		name, found := resolve(vfs.Layers[i], CleanAssetPath(theName))
+		if !found {
+			continue
+		}
+		entries, err := fs.ReadDir(vfs.Layers[i], name)
+		if err != nil {
+			continue
+		}
+		found_dir = true
+		for _, entry := range entries {
+			merged[strings.ToLower(entry.Name())] = entry
+		}
+	}
+	if !found_dir {
+		return nil, &fs.PathError{Op: "readdir", Path: theName, Err: fs.ErrNotExist}
+	}
+	entries := slices.Collect(maps.Values(merged))
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	return entries, nil
+}
+
+// fileType is the extension raylib picks the decoder of in-memory files by.
+func fileType(theFilePath string) string {
+	return strings.ToLower(path.Ext(theFilePath))
+}
+
+// LoadAssetImage decodes an image file of the VFS into CPU memory.
+func LoadAssetImage(theFilePath string) (*rl.Image, error) {
+	data, err := globalVFS.ReadFile(theFilePath)
+	if err != nil {
+		return nil, err
+	}
+	image := rl.LoadImageFromMemory(fileType(theFilePath), data, int32(len(data)))
+	if !rl.IsImageValid(image) {
+		return nil, fmt.Errorf("%s: cannot decode the image", theFilePath)
+	}
+	return image, nil
+}
+
+// LoadAssetSound loads a sound of the VFS. In headless mode the sound is only decoded to check it.
+func LoadAssetSound(theFilePath string) (rl.Sound, error) {
+	data, err := globalVFS.ReadFile(theFilePath)
+	if err != nil {
+		return rl.Sound{}, err
+	}
+	wave := rl.LoadWaveFromMemory(fileType(theFilePath), data, int32(len(data)))
+	if !rl.IsWaveValid(wave) {
+		return rl.Sound{}, fmt.Errorf("%s: cannot decode the sound", theFilePath)
+	}
+	defer rl.UnloadWave(wave)
+	if globalHeadless {
+		return rl.Sound{}, nil
+	}
+	return rl.LoadSoundFromWave(wave), nil
+}
+
+// LoadAssetMusic opens a music stream of the VFS. The stream decodes the returned data while it plays,
+// so the data has to be kept until the stream is unloaded.
+func LoadAssetMusic(theFilePath string) (rl.Music, []byte, error) {
+	data, err := globalVFS.ReadFile(theFilePath)
+	if err != nil {
+		return rl.Music{}, nil, err
+	}
+	music := rl.LoadMusicStreamFromMemory(fileType(theFilePath), data, int32(len(data)))
+	if !rl.IsMusicValid(music) {
+		return rl.Music{}, nil, fmt.Errorf("%s: cannot decode the music", theFilePath)
+	}
+	return music, data, nil
+}
@@ -179,8 +179,7 @@ func (mgr *WayPointMgr) InTunnel2(theBall *Ball, inFront bool) bool {
 	}
 }
 
-func (mgr *WayPointMgr) LoadCurve(filePath string) {
-	path_points := LoadCurveData(filePath)
+func (mgr *WayPointMgr) LoadCurve(path_points []PathPoint) {
 	for i := range path_points {
 		mgr.WayPoints = append(mgr.WayPoints, WayPoint{
 			HasPerpendicular: false,
@@ -1,7 +1,8 @@
 package main
 
 type LevelDesc struct {
-	Name, DisplayName, ImagePath               string
+	Name, DisplayName, ImagePath, Theme        string
+	SettingsId                                 string // the settings applied by MakeLevel, if any
 	FireSpeed                                  float32
 	ReloadDelay                                int32
 	FrogX, FrogY                               int32
@@ -14,6 +15,15 @@ type LevelDesc struct {
 	BackgroundAlphas                           []SpriteDesc
 }
 
+type StageDesc struct {
+	Stage  int32
+	Levels []StageLevel
+}
+
+type StageLevel struct {
+	GraphicsId, SettingsId string
+}
+
 type TreasurePoint struct {
 	X, Y      int32
 	CurveDist []int32
@@ -45,6 +55,28 @@ func NewLevelDesc() *LevelDesc {
 	}
 }
 
+func (desc *LevelDesc) ApplySettings(theSettings *LevelDescModify) {
+	if theSettings.FireSpeed != nil {
+		desc.FireSpeed = *theSettings.FireSpeed
+	}
+	if theSettings.ReloadDelay != nil {
+		desc.ReloadDelay = *theSettings.ReloadDelay
+	}
+	if theSettings.TreasureFreq != nil {
+		desc.TreasureFreq = *theSettings.TreasureFreq
+	}
+	if theSettings.IsInSpace != nil {
+		desc.IsInSpace = *theSettings.IsInSpace
+	}
+	desc.ParTime = theSettings.ParTime
+	for i := range desc.CurveDescs {
+		curve_desc := theSettings.CurveDesc
+		curve_desc.FilePath = desc.CurveDescs[i].FilePath
+		curve_desc.SkullRotation = desc.CurveDescs[i].SkullRotation
+		desc.CurveDescs[i] = curve_desc
+	}
+}
+
 type CurveDesc struct {
 	FilePath           string
 	DangerDistance     int32